@@ -1,55 +1,364 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/appthrust/kutelog/pkg/core"
 	"github.com/appthrust/kutelog/pkg/emitters/fanout"
+	"github.com/appthrust/kutelog/pkg/emitters/file"
+	"github.com/appthrust/kutelog/pkg/emitters/filter"
+	redactemitter "github.com/appthrust/kutelog/pkg/emitters/redact"
 	"github.com/appthrust/kutelog/pkg/emitters/stdout"
 	"github.com/appthrust/kutelog/pkg/emitters/websocket"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/history"
+	"github.com/appthrust/kutelog/pkg/parsers/klog"
+	"github.com/appthrust/kutelog/pkg/parsers/logfmt"
 	"github.com/appthrust/kutelog/pkg/parsers/logr"
+	"github.com/appthrust/kutelog/pkg/parsers/multiline"
 	"github.com/appthrust/kutelog/pkg/parsers/multiple"
+	"github.com/appthrust/kutelog/pkg/parsers/slog"
+	"github.com/appthrust/kutelog/pkg/parsers/zap"
+	"github.com/appthrust/kutelog/pkg/query"
+	"github.com/appthrust/kutelog/pkg/receivers/command"
+	"github.com/appthrust/kutelog/pkg/receivers/kube"
+	"github.com/appthrust/kutelog/pkg/receivers/replay"
 	"github.com/appthrust/kutelog/pkg/receriver"
+	"github.com/appthrust/kutelog/pkg/redact"
+	"github.com/appthrust/kutelog/pkg/stack"
 	"github.com/appthrust/kutelog/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+// kubeFlags holds the flags of the logs subcommand
+type kubeFlags struct {
+	namespace     *string
+	selector      *string
+	kubeconfig    *string
+	context       *string
+	container     *string
+	tail          *int64
+	allNamespaces *bool
+}
+
+// replayFlags holds the flags of the replay subcommand
+type replayFlags struct {
+	speed  *float64
+	maxGap *time.Duration
+}
+
 func main() {
-	showVersion := flag.Bool("version", false, "show version")
-	verbose := flag.Bool("verbose", false, "enable verbose output")
-	flag.Parse()
+	// `kutelog logs` follows pod logs via the Kubernetes API instead of reading stdin,
+	// `kutelog replay file` serves a saved session or log file,
+	// and `kutelog -- command args...` runs the command and reads its output
+	flags := flag.CommandLine
+	args := os.Args[1:]
+	var logsFlags *kubeFlags
+	var replayOptions *replayFlags
+	if len(args) > 0 && args[0] == "replay" {
+		flags = flag.NewFlagSet("replay", flag.ExitOnError)
+		flags.Usage = func() {
+			fmt.Fprintf(flags.Output(), "Usage: %s replay [flags] file\n", version.Name)
+			flags.PrintDefaults()
+		}
+		args = args[1:]
+		replayOptions = &replayFlags{
+			speed:  flags.Float64("speed", 0, "replay at the recorded timing sped up by this factor, e.g. 1 for the original timing (0 for all at once)"),
+			maxGap: flags.Duration("max-gap", 10*time.Second, "longest wait between two messages when replaying at the recorded timing (0 for no limit)"),
+		}
+	} else if len(args) > 0 && args[0] == "logs" {
+		flags = flag.NewFlagSet("logs", flag.ExitOnError)
+		args = args[1:]
+		logsFlags = &kubeFlags{
+			namespace:     flags.String("namespace", "", "namespace of the pods (defaults to the namespace of the kubeconfig context)"),
+			selector:      flags.String("selector", "", "label selector of the pods, e.g. app=controller"),
+			kubeconfig:    flags.String("kubeconfig", "", "path to the kubeconfig file"),
+			context:       flags.String("context", "", "kubeconfig context to use"),
+			container:     flags.String("container", "", "only follow containers with this name"),
+			tail:          flags.Int64("tail", -1, "lines of past logs to show per container (-1 for all)"),
+			allNamespaces: flags.Bool("all-namespaces", false, "follow pods in all namespaces"),
+		}
+		flags.StringVar(logsFlags.namespace, "n", "", "shorthand for -namespace")
+		flags.StringVar(logsFlags.selector, "l", "", "shorthand for -selector")
+		flags.StringVar(logsFlags.container, "c", "", "shorthand for -container")
+		flags.BoolVar(logsFlags.allNamespaces, "A", false, "shorthand for -all-namespaces")
+	}
+	showVersion := flags.Bool("version", false, "show version")
+	verbose := flags.Bool("verbose", false, "enable verbose output")
+	format := flags.String("format", string(stdout.FormatPretty), "format of the -verbose output: pretty, logfmt or json")
+	color := flags.String("color", string(stdout.ColorAuto), "colors of the pretty -verbose output: auto, always or never")
+	exitOnEOF := flags.Bool("exit-on-eof", false, "exit once the input ends instead of keeping the viewer running")
+	name := flags.String("name", "", "name shown as the source of logs read from stdin or the command")
+	historySize := flags.Int("history-size", history.DefaultMaxEntries, "maximum number of messages kept for replay (0 for unlimited)")
+	historyBytes := flags.Int64("history-bytes", 0, "maximum total size in bytes of messages kept for replay (0 for unlimited)")
+	historyDir := flags.String("history-dir", "", "persist message history to segment files in this directory")
+	historySegmentBytes := flags.Int64("history-segment-bytes", history.DefaultSegmentBytes, "size in bytes at which a new history segment file is started")
+	historySegments := flags.Int("history-segments", history.DefaultMaxSegments, "number of history segment files kept on disk")
+	clientQueueSize := flags.Int("client-queue-size", websocket.DefaultQueueSize, "number of messages queued per browser before it is considered slow")
+	logDir := flags.String("log-dir", "", "also write logs as JSON lines to files in this directory, named after the start of the session")
+	logMaxBytes := flags.Int64("log-max-bytes", 0, "size in bytes at which a new log file is started (0 for unlimited)")
+	logMaxAge := flags.Duration("log-max-age", 0, "age at which a new log file is started, e.g. 1h (0 for unlimited)")
+	logCompress := flags.Bool("log-compress", false, "gzip log files once a new one is started or kutelog exits")
+	filterExpression := flags.String("filter", "", `only show entries matching an expression, e.g. 'level >= warning && data.controller == "machine"'`)
+	var redactKeys, redactPatterns []string
+	flags.Func("redact-key", "also mask the values of this data key, or dotted path from the top of the data (repeatable)", func(key string) error {
+		redactKeys = append(redactKeys, key)
+		return nil
+	})
+	flags.Func("redact-pattern", "also mask matches of this regular expression, or only its groups if it has any (repeatable)", func(pattern string) error {
+		redactPatterns = append(redactPatterns, pattern)
+		return nil
+	})
+	noRedact := flags.Bool("no-redact", false, "turn off the built-in detection of tokens, keys, passwords and Secret data")
+	editor := flags.String("editor", "vscode", "editor opened by the links of stack frames: vscode, cursor, idea, goland, sublime, textmate, a URL template with {file} and {line}, or empty for no links")
+	var pathMap []stack.PathMapping
+	flags.Func("path-map", "rewrite a path prefix of stack frames before linking, e.g. /workspace=$HOME/project for a controller running in a container (repeatable)", func(value string) error {
+		mapping, err := stack.ParsePathMapping(value)
+		if err != nil {
+			return err
+		}
+		pathMap = append(pathMap, mapping)
+		return nil
+	})
+	host := flags.String("host", websocket.DefaultHost, "address the viewer listens on, e.g. 0.0.0.0 inside a devcontainer or ::1")
+	port := flags.Int("port", 0, fmt.Sprintf("port the viewer listens on, failing if it is in use (0 tries %d and the following ports)", websocket.DefaultPort))
+	socket := flags.String("socket", "", "listen on this Unix socket instead of -host and -port")
+	useTLS := flags.Bool("tls", false, "serve the viewer over HTTPS, with a self-signed certificate unless -tls-cert and -tls-key are given")
+	tlsCert := flags.String("tls-cert", "", "serve the viewer over HTTPS with this PEM certificate file")
+	tlsKey := flags.String("tls-key", "", "PEM key file of -tls-cert")
+	token := flags.String("token", "", "access token of the viewer, e.g. to keep the URL across restarts (default a random token per run)")
+	noAuth := flags.Bool("no-auth", false, "serve the viewer without an access token")
+	allowedOrigins := flags.String("allowed-origins", "", "comma separated origins of other web pages allowed to connect, e.g. http://localhost:3000 (* for any)")
+	slowClient := flags.String("slow-client", string(websocket.SlowClientDrop), "what to do with browsers that cannot keep up: drop (messages) or disconnect")
+	if err := flags.Parse(args); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Printf("%s version %s\n", version.Name, version.Version)
 		os.Exit(0)
 	}
 
+	// Compile the filter first so that a typo is reported before anything starts
+	var filterQuery *query.Query
+	if *filterExpression != "" {
+		q, err := query.Compile(*filterExpression)
+		if err != nil {
+			log.Fatalf("invalid -filter: %v", err)
+		}
+		filterQuery = q
+	}
+
+	redactor, err := redact.New(&redact.Options{
+		Keys:            redactKeys,
+		Patterns:        redactPatterns,
+		DisableBuiltins: *noRedact,
+	})
+	if err != nil {
+		log.Fatalf("invalid -redact-pattern: %v", err)
+	}
+
+	var linker *stack.Linker
+	if *editor != "" {
+		if linker, err = stack.NewLinker(&stack.LinkerOptions{Editor: *editor, PathMap: pathMap}); err != nil {
+			log.Fatalf("invalid -editor: %v", err)
+		}
+	}
+
 	// Initialize parsers
 	logrParser := logr.NewParser()
-	multiParser := multiple.NewParser(logrParser)
+	zapParser := zap.NewParser()
+	slogJSONParser := slog.NewJSONParser()
+	klogParser := klog.NewParser()
+	slogTextParser := slog.NewTextParser()
+	logfmtParser := logfmt.NewParser()
+	multilineParser := multiline.NewParser()
+	// slog text lines are also valid logfmt, so the stricter slog parser must come first
+	// Panics, foreign stack traces and YAML are grouped only if no log format matches
+	multiParser := multiple.NewParser(logrParser, zapParser, slogJSONParser, klogParser, slogTextParser, logfmtParser, multilineParser)
 
 	// Initialize receiver with multi-parser
-	receiver := receriver.NewReceiver(multiParser)
+	var receiver core.Receiver
+	if replayOptions != nil {
+		if flags.NArg() != 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		receiver = replay.NewReceiver(&replay.ReceiverOptions{
+			Path:   flags.Arg(0),
+			Parser: multiParser,
+			Speed:  *replayOptions.speed,
+			MaxGap: *replayOptions.maxGap,
+		})
+	} else if logsFlags != nil {
+		kubeReceiver, err := newKubeReceiver(logsFlags, multiParser)
+		if err != nil {
+			log.Fatal(err)
+		}
+		receiver = kubeReceiver
+	} else if flags.NArg() > 0 {
+		receiver = command.NewReceiver(&command.ReceiverOptions{
+			Command: flags.Args(),
+			Name:    *name,
+			Parser:  multiParser,
+		})
+	} else {
+		var source *entry.Source
+		if *name != "" {
+			source = &entry.Source{Name: *name}
+		}
+		receiver = receriver.NewReceiverWithOptions(&receriver.ReceiverOptions{
+			Parser: multiParser,
+			Source: source,
+		})
+	}
+
+	// Initialize message history
+	var messageHistory history.Store
+	if *historyDir != "" {
+		segmentLog, err := history.OpenSegmentLog(&history.SegmentLogOptions{
+			Dir:          *historyDir,
+			SegmentBytes: *historySegmentBytes,
+			MaxSegments:  *historySegments,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		messageHistory = segmentLog
+	} else {
+		messageHistory = history.NewRing(&history.RingOptions{
+			MaxEntries: *historySize,
+			MaxBytes:   *historyBytes,
+		})
+	}
 
 	// Initialize emitters
-	wsEmitter := websocket.NewEmitter()
-	var emitter core.Emitter
+	policy := websocket.SlowClientPolicy(*slowClient)
+	if policy != websocket.SlowClientDrop && policy != websocket.SlowClientDisconnect {
+		log.Fatalf("invalid -slow-client: %s", *slowClient)
+	}
+	var tlsOptions *websocket.TLSOptions
+	if *useTLS || *tlsCert != "" || *tlsKey != "" {
+		if (*tlsCert == "") != (*tlsKey == "") {
+			log.Fatal("-tls-cert and -tls-key must be given together")
+		}
+		tlsOptions = &websocket.TLSOptions{
+			CertFile: *tlsCert,
+			KeyFile:  *tlsKey,
+		}
+	}
+	if *noAuth && *token != "" {
+		log.Fatal("-token and -no-auth cannot be given together")
+	}
+	var origins []string
+	for _, origin := range strings.Split(*allowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	wsEmitter := websocket.NewEmitterWithOptions(&websocket.EmitterOptions{
+		History:        messageHistory,
+		QueueSize:      *clientQueueSize,
+		SlowClient:     policy,
+		Host:           *host,
+		Port:           *port,
+		Socket:         *socket,
+		TLS:            tlsOptions,
+		Token:          *token,
+		DisableAuth:    *noAuth,
+		AllowedOrigins: origins,
+		Linker:         linker,
+	})
+	emitters := []core.Emitter{wsEmitter}
 	if *verbose {
-		stdoutEmitter := stdout.NewEmitter()
-		emitter = fanout.NewEmitter(wsEmitter, stdoutEmitter)
-	} else {
-		emitter = fanout.NewEmitter(wsEmitter)
+		stdoutFormat, err := stdout.ParseFormat(*format)
+		if err != nil {
+			log.Fatalf("invalid -format: %v", err)
+		}
+		colorMode, err := stdout.ParseColorMode(*color)
+		if err != nil {
+			log.Fatalf("invalid -color: %v", err)
+		}
+		stdoutEmitter := stdout.NewEmitterWithOptions(&stdout.EmitterOptions{
+			Format: stdoutFormat,
+			Color:  colorMode,
+		})
+		emitters = append(emitters, stdoutEmitter)
 	}
+	if *logDir != "" {
+		emitters = append(emitters, file.NewEmitter(&file.EmitterOptions{
+			Dir:      *logDir,
+			MaxBytes: *logMaxBytes,
+			MaxAge:   *logMaxAge,
+			Compress: *logCompress,
+		}))
+	}
+	var emitter core.Emitter = fanout.NewEmitter(emitters...)
+	if filterQuery != nil {
+		emitter = filter.NewEmitter(filterQuery, emitter)
+	}
+	// Secrets are masked before anything else sees the entries
+	emitter = redactemitter.NewEmitter(redactor, emitter)
 
 	// Create and start process
 	process := core.NewProcess(&core.ProcessOptions{
 		Receiver: receiver,
 		Emitter:  emitter,
+		// kutelog exits along with the command it runs
+		ExitOnEOF: *exitOnEOF || (replayOptions == nil && flags.NArg() > 0),
 	})
 
-	if err := process.Start(); err != nil {
+	if err := process.Start(context.Background()); err != nil {
+		// exit with the code of the command run by kutelog
+		var exitErr *command.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		log.Fatal(err)
 	}
 }
+
+// newKubeReceiver creates a receiver following the pods selected by the logs subcommand flags
+func newKubeReceiver(flags *kubeFlags, parser receriver.Parser) (*kube.Receiver, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = *flags.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: *flags.context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	namespace := *flags.namespace
+	if *flags.allNamespaces {
+		namespace = ""
+	} else if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return nil, fmt.Errorf("failed to get namespace: %w", err)
+		}
+	}
+	var tailLines *int64
+	if *flags.tail >= 0 {
+		tailLines = flags.tail
+	}
+	return kube.NewReceiver(&kube.ReceiverOptions{
+		Client:        client,
+		Namespace:     namespace,
+		LabelSelector: *flags.selector,
+		Container:     *flags.container,
+		TailLines:     tailLines,
+		Parser:        parser,
+	}), nil
+}
@@ -1,59 +1,161 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/appthrust/kutelog/pkg/entry"
 )
 
+// closeTimeout bounds how long emitters may take to close on shutdown
+const closeTimeout = 10 * time.Second
+
 type Process struct {
-	receiver Receiver
-	emitter  Emitter
+	receiver  Receiver
+	emitter   Emitter
+	exitOnEOF bool
+	input     io.Reader
+	signals   <-chan os.Signal
 }
 
 func NewProcess(options *ProcessOptions) *Process {
+	input := options.Input
+	if input == nil {
+		input = os.Stdin
+	}
 	return &Process{
-		receiver: options.Receiver,
-		emitter:  options.Emitter,
+		receiver:  options.Receiver,
+		emitter:   options.Emitter,
+		exitOnEOF: options.ExitOnEOF,
+		input:     input,
+		signals:   options.Signals,
 	}
 }
 
-func (p *Process) Start() error {
+// Start runs the receiver and emits its entries until the process is stopped by a signal,
+// ctx is canceled, the receiver fails, or the input ends with ExitOnEOF set
+// On shutdown, entries still buffered are emitted before the emitter is closed
+// Start returns nil when stopped by a signal or ctx
+func (p *Process) Start(ctx context.Context) error {
+	receiverCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	entries := make(chan *entry.Entry, 1000)
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	// Must start receiver before emitter since emitter initialization may be slow
-	go p.receiver.Receive(os.Stdin, entries, errChan)
-	if err := p.emitter.Init(); err != nil {
+	go func() {
+		errChan <- p.receiver.Receive(receiverCtx, p.input, entries)
+	}()
+	if err := p.emitter.Init(ctx); err != nil {
 		return fmt.Errorf("failed to initialize emitter: %w", err)
 	}
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	err := p.run(ctx, entries, errChan)
+	cancel()
+	p.drain(entries)
+
+	// Close even if ctx is already canceled, but do not wait forever
+	closeCtx, cancelClose := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+	defer cancelClose()
+	if closeErr := p.emitter.Close(closeCtx); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close emitter: %w", closeErr)
+	}
+	return err
+}
+
+// run emits entries until the process is to be stopped
+func (p *Process) run(ctx context.Context, entries <-chan *entry.Entry, errChan <-chan error) error {
+	signals := p.signals
+	if signals == nil {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sig)
+		signals = sig
+	}
+	forwarded := false // whether a signal was forwarded to the receiver's child
 	for {
 		select {
 		case e := <-entries:
 			p.emitter.Emit(e)
 		case err := <-errChan:
-			return fmt.Errorf("receiver error: %w", err)
-		case <-sig:
+			if ctx.Err() != nil {
+				// the receiver stopped because ctx was canceled
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("receiver error: %w", err)
+			}
+			if p.exitOnEOF {
+				return nil
+			}
+			// Keep serving the entries received so far until stopped
+			errChan = nil
+		case s := <-signals:
+			if forwarder, ok := p.receiver.(SignalForwarder); ok {
+				if !forwarded {
+					// Keep running until the receiver reports that its child has exited
+					forwarder.ForwardSignal(s)
+					forwarded = true
+					continue
+				}
+				// A second signal stops a child that ignores or hangs on the first
+				forwarder.ForwardSignal(os.Kill)
+			}
+			return nil
+		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
+// drain emits the entries left in the channel without waiting for more
+func (p *Process) drain(entries <-chan *entry.Entry) {
+	for {
+		select {
+		case e := <-entries:
+			p.emitter.Emit(e)
+		default:
+			return
+		}
+	}
+}
+
 type ProcessOptions struct {
 	Receiver Receiver
 	Emitter  Emitter
+	// ExitOnEOF stops the process once the input ends
+	// Otherwise the process keeps running so that emitters such as the viewer stay available
+	ExitOnEOF bool
+	// Input is passed to the receiver, defaults to os.Stdin
+	Input io.Reader
+	// Signals stop the process or are forwarded to the receiver's child process
+	// Defaults to SIGINT and SIGTERM received by kutelog
+	Signals <-chan os.Signal
 }
 
+// Receiver reads entries and sends them to entries until input ends or ctx is canceled
+// It returns nil when input ends normally and must send all entries before returning
+// Sends to entries should also select on ctx.Done() so that a stopped process does not block them
 type Receiver interface {
-	Receive(input io.Reader, entries chan<- *entry.Entry, err chan<- error)
+	Receive(ctx context.Context, input io.Reader, entries chan<- *entry.Entry) error
+}
+
+// SignalForwarder is implemented by receivers that run a child process
+// The first signal is forwarded to the child instead of stopping the process;
+// a second one forwards os.Kill and stops the process
+type SignalForwarder interface {
+	ForwardSignal(os.Signal)
 }
 
 type Emitter interface {
-	Init() error
+	Init(ctx context.Context) error
 	Emit(*entry.Entry)
+	// Close releases the resources of the emitter once no more entries are emitted
+	// ctx bounds how long Close may wait, e.g. for clients to disconnect
+	Close(ctx context.Context) error
 }
@@ -0,0 +1,258 @@
+package core_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+// mockReceiver is a test double that sends the given entries and returns err
+// If block is set, it closes sent and keeps running until ctx is canceled
+type mockReceiver struct {
+	entries []*entry.Entry
+	err     error
+	block   bool
+	sent    chan struct{}
+}
+
+func (m *mockReceiver) Receive(ctx context.Context, input io.Reader, entries chan<- *entry.Entry) error {
+	for _, e := range m.entries {
+		entries <- e
+	}
+	if m.block {
+		if m.sent != nil {
+			close(m.sent)
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return m.err
+}
+
+// lineReceiver is a test double that turns each line of input into an unstructured entry
+type lineReceiver struct{}
+
+func (r *lineReceiver) Receive(ctx context.Context, input io.Reader, entries chan<- *entry.Entry) error {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		entries <- &entry.Entry{Unstructured: scanner.Text()}
+	}
+	return scanner.Err()
+}
+
+// forwardingReceiver is a test double that records forwarded signals and ends on SIGTERM
+type forwardingReceiver struct {
+	forwarded chan os.Signal
+	mu        sync.Mutex
+	received  []os.Signal
+}
+
+func (r *forwardingReceiver) Receive(ctx context.Context, input io.Reader, entries chan<- *entry.Entry) error {
+	for s := range r.forwarded {
+		r.mu.Lock()
+		r.received = append(r.received, s)
+		r.mu.Unlock()
+		if s == syscall.SIGTERM {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *forwardingReceiver) ForwardSignal(s os.Signal) {
+	r.forwarded <- s
+}
+
+// signals returns the signals forwarded so far
+func (r *forwardingReceiver) signals() []os.Signal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]os.Signal(nil), r.received...)
+}
+
+// mockEmitter is a test double that records emitted entries
+type mockEmitter struct {
+	mu          sync.Mutex
+	entries     []*entry.Entry
+	closeCalled bool
+}
+
+func (m *mockEmitter) Init(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockEmitter) Emit(e *entry.Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, e)
+}
+
+func (m *mockEmitter) Close(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeCalled = true
+	return nil
+}
+
+func (m *mockEmitter) emitted() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+var _ = Describe("Process", func() {
+	var (
+		emitter *mockEmitter
+		entries []*entry.Entry
+	)
+
+	BeforeEach(func() {
+		emitter = &mockEmitter{}
+		entries = nil
+		for i := 0; i < 500; i++ {
+			entries = append(entries, &entry.Entry{Unstructured: "line"})
+		}
+	})
+
+	It("should emit all entries and close the emitter on EOF with ExitOnEOF", func() {
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver:  &mockReceiver{entries: entries, err: nil},
+			Emitter:   emitter,
+			ExitOnEOF: true,
+		})
+
+		Expect(process.Start(context.Background())).To(Succeed())
+		Expect(emitter.entries).To(HaveLen(len(entries)))
+		Expect(emitter.closeCalled).To(BeTrue())
+	})
+
+	It("should emit all entries and close the emitter on a receiver error", func() {
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver: &mockReceiver{entries: entries, err: errors.New("broken pipe")},
+			Emitter:  emitter,
+		})
+
+		Expect(process.Start(context.Background())).To(MatchError(ContainSubstring("broken pipe")))
+		Expect(emitter.entries).To(HaveLen(len(entries)))
+		Expect(emitter.closeCalled).To(BeTrue())
+	})
+
+	It("should keep running after EOF without ExitOnEOF", func() {
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver: &mockReceiver{entries: entries, err: nil},
+			Emitter:  emitter,
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			done <- process.Start(context.Background())
+		}()
+		Eventually(emitter.emitted).Should(Equal(len(entries)))
+		Consistently(done, 200*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should read the given input", func() {
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver:  &lineReceiver{},
+			Emitter:   emitter,
+			ExitOnEOF: true,
+			Input:     strings.NewReader("first\nsecond\n"),
+		})
+
+		Expect(process.Start(context.Background())).To(Succeed())
+		Expect(emitter.entries).To(Equal([]*entry.Entry{
+			{Unstructured: "first"},
+			{Unstructured: "second"},
+		}))
+	})
+
+	It("should stop on a signal and emit buffered entries", func() {
+		signals := make(chan os.Signal, 1)
+		receiver := &mockReceiver{entries: entries, block: true, sent: make(chan struct{})}
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver: receiver,
+			Emitter:  emitter,
+			Signals:  signals,
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			done <- process.Start(context.Background())
+		}()
+		Eventually(receiver.sent).Should(BeClosed())
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(emitter.entries).To(HaveLen(len(entries)))
+		Expect(emitter.closeCalled).To(BeTrue())
+	})
+
+	It("should stop when ctx is canceled", func() {
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver: &mockReceiver{block: true},
+			Emitter:  emitter,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- process.Start(ctx)
+		}()
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(emitter.closeCalled).To(BeTrue())
+	})
+
+	It("should forward signals to receivers running a child process", func() {
+		signals := make(chan os.Signal, 2)
+		receiver := &forwardingReceiver{forwarded: make(chan os.Signal, 2)}
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver:  receiver,
+			Emitter:   emitter,
+			ExitOnEOF: true,
+			Signals:   signals,
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			done <- process.Start(context.Background())
+		}()
+		signals <- syscall.SIGTERM
+		// the process ends once the child does
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(receiver.signals()).To(Equal([]os.Signal{syscall.SIGTERM}))
+	})
+
+	It("should kill the child on a second signal", func() {
+		signals := make(chan os.Signal, 2)
+		receiver := &forwardingReceiver{forwarded: make(chan os.Signal, 2)}
+		process := core.NewProcess(&core.ProcessOptions{
+			Receiver:  receiver,
+			Emitter:   emitter,
+			ExitOnEOF: true,
+			Signals:   signals,
+		})
+
+		done := make(chan error, 1)
+		go func() {
+			done <- process.Start(context.Background())
+		}()
+		// the child ignores the first signal
+		signals <- os.Interrupt
+		Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+		signals <- os.Interrupt
+		Eventually(done).Should(Receive(BeNil()))
+		Eventually(receiver.signals).Should(Equal([]os.Signal{os.Interrupt, os.Kill}))
+	})
+})
@@ -1,12 +1,16 @@
 package fanout
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/appthrust/kutelog/pkg/core"
 	"github.com/appthrust/kutelog/pkg/entry"
 )
 
+var _ core.Emitter = &Emitter{}
+
 // Emitter broadcasts log entries to multiple emitters
 type Emitter struct {
 	emitters []core.Emitter
@@ -20,9 +24,9 @@ func NewEmitter(emitters ...core.Emitter) *Emitter {
 }
 
 // Init initializes all underlying emitters
-func (e *Emitter) Init() error {
+func (e *Emitter) Init(ctx context.Context) error {
 	for _, emitter := range e.emitters {
-		if err := emitter.Init(); err != nil {
+		if err := emitter.Init(ctx); err != nil {
 			return fmt.Errorf("failed to initialize emitter: %w", err)
 		}
 	}
@@ -35,3 +39,14 @@ func (e *Emitter) Emit(entry *entry.Entry) {
 		emitter.Emit(entry)
 	}
 }
+
+// Close closes all underlying emitters, even if some of them fail
+func (e *Emitter) Close(ctx context.Context) error {
+	var errs []error
+	for _, emitter := range e.emitters {
+		if err := emitter.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close emitter: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,6 +1,7 @@
 package fanout_test
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -12,12 +13,14 @@ import (
 
 // mockEmitter is a test double that implements the core.Emitter interface
 type mockEmitter struct {
-	initErr    error
-	initCalled bool
-	entries    []*entry.Entry
+	initErr     error
+	initCalled  bool
+	closeErr    error
+	closeCalled bool
+	entries     []*entry.Entry
 }
 
-func (m *mockEmitter) Init() error {
+func (m *mockEmitter) Init(ctx context.Context) error {
 	m.initCalled = true
 	return m.initErr
 }
@@ -26,6 +29,11 @@ func (m *mockEmitter) Emit(e *entry.Entry) {
 	m.entries = append(m.entries, e)
 }
 
+func (m *mockEmitter) Close(ctx context.Context) error {
+	m.closeCalled = true
+	return m.closeErr
+}
+
 var _ = Describe("Fanout Emitter", func() {
 	var (
 		mock1   *mockEmitter
@@ -41,19 +49,19 @@ var _ = Describe("Fanout Emitter", func() {
 
 	Context("when initializing", func() {
 		It("initializes all emitters successfully", func() {
-			Expect(emitter.Init()).To(Succeed())
+			Expect(emitter.Init(context.Background())).To(Succeed())
 			Expect(mock1.initCalled).To(BeTrue(), "expected first emitter to be initialized")
 			Expect(mock2.initCalled).To(BeTrue(), "expected second emitter to be initialized")
 		})
 
 		It("handles initialization error", func() {
 			mock2.initErr = errors.New("init failed")
-			Expect(emitter.Init()).To(HaveOccurred())
+			Expect(emitter.Init(context.Background())).To(HaveOccurred())
 		})
 
 		It("works with no emitters", func() {
 			emitter = fanout.NewEmitter()
-			Expect(emitter.Init()).To(Succeed())
+			Expect(emitter.Init(context.Background())).To(Succeed())
 		})
 	})
 
@@ -77,4 +85,18 @@ var _ = Describe("Fanout Emitter", func() {
 			}).NotTo(Panic())
 		})
 	})
+
+	Context("when closing", func() {
+		It("closes all emitters", func() {
+			Expect(emitter.Close(context.Background())).To(Succeed())
+			Expect(mock1.closeCalled).To(BeTrue(), "expected first emitter to be closed")
+			Expect(mock2.closeCalled).To(BeTrue(), "expected second emitter to be closed")
+		})
+
+		It("closes remaining emitters after an error", func() {
+			mock1.closeErr = errors.New("close failed")
+			Expect(emitter.Close(context.Background())).To(MatchError(ContainSubstring("close failed")))
+			Expect(mock2.closeCalled).To(BeTrue(), "expected second emitter to be closed")
+		})
+	})
 })
@@ -0,0 +1,233 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+const (
+	// DefaultPrefix is the default start of the names of the files
+	DefaultPrefix = "kutelog"
+
+	// Ext is the extension of the files, followed by GzipExt once they are compressed
+	Ext = ".jsonl"
+	// GzipExt is appended to the name of compressed files
+	GzipExt = ".gz"
+
+	sessionLayout = "20060102-150405"
+)
+
+var _ core.Emitter = &Emitter{}
+
+// EmitterOptions configures where and how entries are written
+type EmitterOptions struct {
+	Dir      string        // directory of the files, created if missing
+	Prefix   string        // start of the file names; defaults to DefaultPrefix
+	MaxBytes int64         // size at which a new file is started; 0 for no limit
+	MaxAge   time.Duration // age at which a new file is started; 0 for no limit
+	Compress bool          // gzip each file once the next one is started or the emitter is closed
+}
+
+// Emitter writes entries as JSON lines to files named after the start of the session, e.g.
+// kutelog-20250130-155237-001.jsonl, starting a new numbered file when the current one
+// grows too large or too old
+type Emitter struct {
+	options EmitterOptions
+
+	mu       sync.Mutex
+	session  time.Time
+	sequence int
+	file     *os.File // file currently written; nil before Init and after Close
+	bytes    int64
+	openedAt time.Time
+	err      error // first write or compression error, reported by Close
+
+	compressing sync.WaitGroup
+}
+
+// NewEmitter creates a new file emitter with the given options
+func NewEmitter(options *EmitterOptions) *Emitter {
+	e := &Emitter{options: *options}
+	if e.options.Prefix == "" {
+		e.options.Prefix = DefaultPrefix
+	}
+	return e
+}
+
+// Init creates the directory and the first file of the session
+func (e *Emitter) Init(ctx context.Context) error {
+	if err := os.MkdirAll(e.options.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.session = time.Now()
+	if err := e.open(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Writing logs to %s\n", e.file.Name())
+	return nil
+}
+
+// Path returns the path of the file currently written
+func (e *Emitter) Path() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.path(e.sequence)
+}
+
+func (e *Emitter) path(sequence int) string {
+	name := fmt.Sprintf("%s-%s-%03d%s", e.options.Prefix, e.session.Format(sessionLayout), sequence, Ext)
+	return filepath.Join(e.options.Dir, name)
+}
+
+// open starts the next file of the session
+func (e *Emitter) open() error {
+	e.sequence++
+	file, err := os.OpenFile(e.path(e.sequence), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+	e.file = file
+	e.bytes = 0
+	e.openedAt = time.Now()
+	return nil
+}
+
+// Emit appends the entry to the current file, starting a new file first if the current one is full
+func (e *Emitter) Emit(entry *entry.Entry) {
+	if entry == nil || (entry.Structured == nil && entry.Unstructured == "") {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return
+	}
+	if e.bytes > 0 && e.full(int64(len(line))) {
+		if err := e.rotate(); err != nil {
+			e.fail(err)
+			return
+		}
+	}
+	n, err := e.file.Write(line)
+	e.bytes += int64(n)
+	if err != nil {
+		e.fail(fmt.Errorf("failed to write log file: %w", err))
+	}
+}
+
+// full reports whether the current file has no room for n more bytes or is too old
+func (e *Emitter) full(n int64) bool {
+	if e.options.MaxBytes > 0 && e.bytes+n > e.options.MaxBytes {
+		return true
+	}
+	return e.options.MaxAge > 0 && time.Since(e.openedAt) >= e.options.MaxAge
+}
+
+// rotate closes the current file, compressing it in the background, and opens the next one
+func (e *Emitter) rotate() error {
+	if err := e.closeFile(); err != nil {
+		return err
+	}
+	return e.open()
+}
+
+func (e *Emitter) closeFile() error {
+	path := e.file.Name()
+	err := e.file.Close()
+	e.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	if e.options.Compress {
+		e.compressing.Add(1)
+		go func() {
+			defer e.compressing.Done()
+			if err := compress(path); err != nil {
+				e.mu.Lock()
+				e.fail(err)
+				e.mu.Unlock()
+			}
+		}()
+	}
+	return nil
+}
+
+// compress replaces the file with a gzipped copy
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+	defer src.Close()
+
+	// write to a temporary file so that a partial archive is never mistaken for a complete one
+	tmpPath := path + GzipExt + ".tmp"
+	dst, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	err = errors.Join(err, gz.Close(), dst.Close())
+	if err == nil {
+		err = os.Rename(tmpPath, path+GzipExt)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+	return os.Remove(path)
+}
+
+// Close closes the current file and waits for compression to finish,
+// returning the first error that occurred while writing
+func (e *Emitter) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if e.file != nil {
+		if err := e.closeFile(); err != nil {
+			e.fail(err)
+		}
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.compressing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to finish compressing log files: %w", ctx.Err())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// fail records err unless an earlier error was recorded; e.mu must be held
+func (e *Emitter) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
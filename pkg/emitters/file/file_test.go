@@ -0,0 +1,147 @@
+package file_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/emitters/file"
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+var _ = Describe("File Emitter", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	// readEntries decodes the entries of a plain or gzipped file
+	readEntries := func(path string) []*entry.Entry {
+		f, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		var r io.Reader = f
+		if strings.HasSuffix(path, file.GzipExt) {
+			gz, err := gzip.NewReader(f)
+			Expect(err).NotTo(HaveOccurred())
+			r = gz
+		}
+		var entries []*entry.Entry
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var e entry.Entry
+			Expect(json.Unmarshal(scanner.Bytes(), &e)).To(Succeed())
+			entries = append(entries, &e)
+		}
+		Expect(scanner.Err()).NotTo(HaveOccurred())
+		return entries
+	}
+
+	files := func() []string {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"))
+		Expect(err).NotTo(HaveOccurred())
+		return paths
+	}
+
+	It("writes structured and unstructured entries as JSON lines", func() {
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: dir})
+		Expect(emitter.Init(context.Background())).To(Succeed())
+
+		structured := &entry.Entry{
+			Structured: &entry.Structured{
+				Timestamp: time.Date(2025, 1, 30, 15, 52, 37, 0, time.UTC),
+				Level:     entry.LevelError,
+				Message:   "reconcile failed",
+				Data:      map[string]interface{}{"controller": "machine"},
+				Stack:     "main.main()",
+			},
+			Source: &entry.Source{Pod: "web-0"},
+		}
+		unstructured := &entry.Entry{Unstructured: "plain text log"}
+		emitter.Emit(structured)
+		emitter.Emit(unstructured)
+		emitter.Emit(&entry.Entry{})
+		Expect(emitter.Close(context.Background())).To(Succeed())
+
+		Expect(files()).To(HaveLen(1))
+		Expect(filepath.Base(files()[0])).To(MatchRegexp(`^kutelog-\d{8}-\d{6}-001\.jsonl$`))
+		Expect(readEntries(files()[0])).To(Equal([]*entry.Entry{structured, unstructured}))
+	})
+
+	It("names files after the prefix and the session start", func() {
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: filepath.Join(dir, "logs"), Prefix: "bug-123"})
+		before := time.Now().Truncate(time.Second)
+		Expect(emitter.Init(context.Background())).To(Succeed())
+		defer emitter.Close(context.Background())
+
+		name := filepath.Base(emitter.Path())
+		Expect(name).To(HavePrefix("bug-123-"))
+		session, err := time.ParseInLocation("20060102-150405", strings.TrimPrefix(strings.TrimSuffix(name, "-001.jsonl"), "bug-123-"), time.Local)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(session).To(BeTemporally(">=", before))
+	})
+
+	It("starts a new file when the current one reaches the size limit", func() {
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: dir, MaxBytes: 100})
+		Expect(emitter.Init(context.Background())).To(Succeed())
+		for i := 0; i < 5; i++ {
+			emitter.Emit(&entry.Entry{Unstructured: strings.Repeat("x", 60)})
+		}
+		Expect(emitter.Close(context.Background())).To(Succeed())
+
+		paths := files()
+		Expect(paths).To(HaveLen(5))
+		for i, path := range paths {
+			Expect(path).To(HaveSuffix("-00" + string(rune('1'+i)) + file.Ext))
+			Expect(readEntries(path)).To(HaveLen(1))
+		}
+	})
+
+	It("starts a new file when the current one reaches the age limit", func() {
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: dir, MaxAge: 50 * time.Millisecond})
+		Expect(emitter.Init(context.Background())).To(Succeed())
+		emitter.Emit(&entry.Entry{Unstructured: "first"})
+		emitter.Emit(&entry.Entry{Unstructured: "second"})
+		time.Sleep(60 * time.Millisecond)
+		emitter.Emit(&entry.Entry{Unstructured: "third"})
+		Expect(emitter.Close(context.Background())).To(Succeed())
+
+		paths := files()
+		Expect(paths).To(HaveLen(2))
+		Expect(readEntries(paths[0])).To(HaveLen(2))
+		Expect(readEntries(paths[1])).To(HaveLen(1))
+	})
+
+	It("compresses files once they are rotated", func() {
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: dir, MaxBytes: 100, Compress: true})
+		Expect(emitter.Init(context.Background())).To(Succeed())
+		for i := 0; i < 3; i++ {
+			emitter.Emit(&entry.Entry{Unstructured: strings.Repeat("x", 60)})
+		}
+		Expect(emitter.Close(context.Background())).To(Succeed())
+
+		paths := files()
+		Expect(paths).To(HaveLen(3))
+		for _, path := range paths {
+			Expect(path).To(HaveSuffix(file.Ext + file.GzipExt))
+			Expect(readEntries(path)).To(Equal([]*entry.Entry{{Unstructured: strings.Repeat("x", 60)}}))
+		}
+	})
+
+	It("fails to initialize if the directory cannot be created", func() {
+		blocker := filepath.Join(dir, "file")
+		Expect(os.WriteFile(blocker, nil, 0o644)).To(Succeed())
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: filepath.Join(blocker, "logs")})
+		Expect(emitter.Init(context.Background())).To(MatchError(ContainSubstring("failed to create log directory")))
+	})
+})
@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"context"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/query"
+)
+
+var _ core.Emitter = &Emitter{}
+
+// Emitter passes only the entries matching a query to another emitter
+type Emitter struct {
+	query   *query.Query
+	emitter core.Emitter
+}
+
+// NewEmitter creates an emitter passing entries matching q to emitter
+func NewEmitter(q *query.Query, emitter core.Emitter) *Emitter {
+	return &Emitter{
+		query:   q,
+		emitter: emitter,
+	}
+}
+
+// Init initializes the underlying emitter
+func (e *Emitter) Init(ctx context.Context) error {
+	return e.emitter.Init(ctx)
+}
+
+// Emit passes the entry to the underlying emitter if it matches the query
+func (e *Emitter) Emit(entry *entry.Entry) {
+	if entry == nil || !e.query.Match(entry) {
+		return
+	}
+	e.emitter.Emit(entry)
+}
+
+// Close closes the underlying emitter
+func (e *Emitter) Close(ctx context.Context) error {
+	return e.emitter.Close(ctx)
+}
@@ -0,0 +1,66 @@
+package filter_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/emitters/filter"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/query"
+)
+
+// mockEmitter is a test double that implements the core.Emitter interface
+type mockEmitter struct {
+	initErr     error
+	closeCalled bool
+	entries     []*entry.Entry
+}
+
+func (m *mockEmitter) Init(ctx context.Context) error {
+	return m.initErr
+}
+
+func (m *mockEmitter) Emit(e *entry.Entry) {
+	m.entries = append(m.entries, e)
+}
+
+func (m *mockEmitter) Close(ctx context.Context) error {
+	m.closeCalled = true
+	return nil
+}
+
+var _ = Describe("Filter Emitter", func() {
+	var (
+		mock    *mockEmitter
+		emitter *filter.Emitter
+	)
+
+	BeforeEach(func() {
+		mock = &mockEmitter{}
+		emitter = filter.NewEmitter(query.MustCompile(`level >= warning`), mock)
+	})
+
+	It("emits only matching entries", func() {
+		warning := &entry.Entry{Structured: &entry.Structured{Level: entry.LevelWarning, Message: "warning"}}
+		emitter.Emit(&entry.Entry{Structured: &entry.Structured{Level: entry.LevelDebug, Message: "debug"}})
+		emitter.Emit(&entry.Entry{Unstructured: "plain"})
+		emitter.Emit(warning)
+		Expect(mock.entries).To(Equal([]*entry.Entry{warning}))
+	})
+
+	It("emits everything without a query", func() {
+		emitter = filter.NewEmitter(nil, mock)
+		emitter.Emit(&entry.Entry{Unstructured: "plain"})
+		Expect(mock.entries).To(HaveLen(1))
+	})
+
+	It("delegates Init and Close", func() {
+		mock.initErr = errors.New("init failed")
+		Expect(emitter.Init(context.Background())).To(MatchError("init failed"))
+		Expect(emitter.Close(context.Background())).To(Succeed())
+		Expect(mock.closeCalled).To(BeTrue())
+	})
+})
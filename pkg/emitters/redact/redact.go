@@ -0,0 +1,43 @@
+package redact
+
+import (
+	"context"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/redact"
+)
+
+var _ core.Emitter = &Emitter{}
+
+// Emitter masks secrets in entries before passing them to another emitter
+type Emitter struct {
+	redactor *redact.Redactor
+	emitter  core.Emitter
+}
+
+// NewEmitter creates an emitter passing entries redacted by redactor to emitter
+func NewEmitter(redactor *redact.Redactor, emitter core.Emitter) *Emitter {
+	return &Emitter{
+		redactor: redactor,
+		emitter:  emitter,
+	}
+}
+
+// Init initializes the underlying emitter
+func (e *Emitter) Init(ctx context.Context) error {
+	return e.emitter.Init(ctx)
+}
+
+// Emit passes a redacted copy of the entry to the underlying emitter
+func (e *Emitter) Emit(entry *entry.Entry) {
+	if entry == nil {
+		return
+	}
+	e.emitter.Emit(e.redactor.Entry(entry))
+}
+
+// Close closes the underlying emitter
+func (e *Emitter) Close(ctx context.Context) error {
+	return e.emitter.Close(ctx)
+}
@@ -0,0 +1,69 @@
+package redact_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	redactemitter "github.com/appthrust/kutelog/pkg/emitters/redact"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/redact"
+)
+
+// mockEmitter is a test double that implements the core.Emitter interface
+type mockEmitter struct {
+	initErr     error
+	closeCalled bool
+	entries     []*entry.Entry
+}
+
+func (m *mockEmitter) Init(ctx context.Context) error {
+	return m.initErr
+}
+
+func (m *mockEmitter) Emit(e *entry.Entry) {
+	m.entries = append(m.entries, e)
+}
+
+func (m *mockEmitter) Close(ctx context.Context) error {
+	m.closeCalled = true
+	return nil
+}
+
+var _ = Describe("Redact Emitter", func() {
+	var (
+		mock    *mockEmitter
+		emitter *redactemitter.Emitter
+	)
+
+	BeforeEach(func() {
+		redactor, err := redact.New(&redact.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		mock = &mockEmitter{}
+		emitter = redactemitter.NewEmitter(redactor, mock)
+	})
+
+	It("emits redacted copies of entries", func() {
+		original := &entry.Entry{Structured: &entry.Structured{
+			Level:   entry.LevelInfo,
+			Message: "logged in",
+			Data:    map[string]interface{}{"password": "hunter2"},
+		}}
+		emitter.Emit(original)
+		emitter.Emit(&entry.Entry{Unstructured: "password=hunter2"})
+
+		Expect(mock.entries).To(HaveLen(2))
+		Expect(mock.entries[0].Structured.Data).To(HaveKeyWithValue("password", redact.Mask))
+		Expect(mock.entries[1].Unstructured).To(Equal("password=" + redact.Mask))
+		Expect(original.Structured.Data).To(HaveKeyWithValue("password", "hunter2"))
+	})
+
+	It("delegates Init and Close", func() {
+		mock.initErr = errors.New("init failed")
+		Expect(emitter.Init(context.Background())).To(MatchError("init failed"))
+		Expect(emitter.Close(context.Background())).To(Succeed())
+		Expect(mock.closeCalled).To(BeTrue())
+	})
+})
@@ -0,0 +1,224 @@
+package stdout
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+// ANSI escape sequences used by FormatPretty
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+	ansiRed   = "\x1b[31m"
+)
+
+// levelBadges are the labels and colors of the level badges of FormatPretty
+var levelBadges = map[entry.Level]struct {
+	label string
+	color string
+}{
+	entry.LevelTrace:   {"TRACE", "\x1b[30;47m"},
+	entry.LevelDebug:   {"DEBUG", "\x1b[30;44m"},
+	entry.LevelInfo:    {"INFO", "\x1b[30;42m"},
+	entry.LevelWarning: {"WARN", "\x1b[30;43m"},
+	entry.LevelError:   {"ERROR", "\x1b[30;41m"},
+	entry.LevelPanic:   {"PANIC", "\x1b[97;45m"},
+	entry.LevelFatal:   {"FATAL", "\x1b[97;45m"},
+}
+
+// badgeWidth is the width of the longest label, so that messages line up
+const badgeWidth = 5
+
+// dataIndent indents the data and stack lines under the message
+const dataIndent = "    "
+
+// writePretty writes the entry for reading in a terminal, e.g.
+//
+//	+00:00:01.234 INFO  [default/web-0:app] starting manager
+//	    controller: machine
+func (e *Emitter) writePretty(en *entry.Entry) {
+	timestamp := time.Now()
+	if en.Structured != nil {
+		timestamp = en.Structured.Timestamp
+	}
+	e.style(ansiDim, formatElapsed(timestamp.Sub(e.start)))
+	e.buf.WriteByte(' ')
+
+	if en.Structured == nil {
+		// unstructured lines get a blank badge to stay aligned with structured ones
+		e.buf.WriteString(strings.Repeat(" ", e.badgeLength()+1))
+		e.writeSource(en.Source)
+		e.buf.WriteString(en.Unstructured)
+		e.buf.WriteByte('\n')
+		return
+	}
+
+	e.writeBadge(en.Structured)
+	e.buf.WriteByte(' ')
+	e.writeSource(en.Source)
+	if en.Structured.Level.Severity() >= entry.LevelError.Severity() {
+		e.style(ansiRed, en.Structured.Message)
+	} else {
+		e.buf.WriteString(en.Structured.Message)
+	}
+	e.buf.WriteByte('\n')
+
+	for _, key := range sortedKeys(en.Structured.Data) {
+		e.buf.WriteString(dataIndent)
+		e.style(ansiDim, key+":")
+		e.buf.WriteByte(' ')
+		e.buf.WriteString(entry.FormatValue(en.Structured.Data[key]))
+		e.buf.WriteByte('\n')
+	}
+	if en.Structured.Stack != "" {
+		for _, line := range strings.Split(strings.TrimRight(en.Structured.Stack, "\n"), "\n") {
+			e.buf.WriteString(dataIndent)
+			e.style(ansiDim, line)
+			e.buf.WriteByte('\n')
+		}
+	}
+}
+
+// badgeLength is the width of a badge including its padding
+func (e *Emitter) badgeLength() int {
+	if e.color {
+		return badgeWidth + 2
+	}
+	return badgeWidth
+}
+
+func (e *Emitter) writeBadge(structured *entry.Structured) {
+	badge, ok := levelBadges[structured.Level]
+	if !ok {
+		badge = levelBadges[entry.LevelInfo]
+		badge.label = strings.ToUpper(string(structured.Level))
+	}
+	if structured.Level == entry.LevelDebug && structured.Verbosity > 0 {
+		badge.label = "V" + strconv.Itoa(structured.Verbosity)
+	}
+	label := fmt.Sprintf("%-*s", badgeWidth, badge.label)
+	if e.color {
+		e.style(badge.color, " "+label+" ")
+	} else {
+		e.buf.WriteString(label)
+	}
+}
+
+func (e *Emitter) writeSource(source *entry.Source) {
+	label := source.String()
+	if label == "" {
+		return
+	}
+	e.style(ansiCyan, "["+label+"]")
+	e.buf.WriteByte(' ')
+}
+
+// style writes text in the given color if colors are enabled
+func (e *Emitter) style(color, text string) {
+	if !e.color {
+		e.buf.WriteString(text)
+		return
+	}
+	e.buf.WriteString(color)
+	e.buf.WriteString(text)
+	e.buf.WriteString(ansiReset)
+}
+
+// formatElapsed formats the time since start as +hh:mm:ss.mmm, negative for entries logged before
+func formatElapsed(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	d = d.Truncate(time.Millisecond)
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	millis := (d % time.Second) / time.Millisecond
+	return fmt.Sprintf("%s%02d:%02d:%02d.%03d", sign, hours, minutes, seconds, millis)
+}
+
+// writeJSON writes the structured entry as a compact JSON line
+func (e *Emitter) writeJSON(en *entry.Entry) {
+	encoder := json.NewEncoder(&e.buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(struct {
+		*entry.Structured
+		Source *entry.Source `json:"source,omitempty"`
+	}{en.Structured, en.Source})
+}
+
+// writeLogfmt writes the structured entry as a logfmt line
+func (e *Emitter) writeLogfmt(en *entry.Entry) {
+	structured := en.Structured
+	e.writePair("time", structured.Timestamp.Format(time.RFC3339Nano))
+	e.writePair("level", string(structured.Level))
+	if structured.Verbosity > 0 {
+		e.writePair("v", strconv.Itoa(structured.Verbosity))
+	}
+	e.writePair("msg", structured.Message)
+
+	flat := structured.FlatData()
+	for _, key := range sortedKeys(flat) {
+		e.writePair(key, entry.FormatValue(flat[key]))
+	}
+	if source := en.Source; source != nil {
+		for _, field := range []struct{ key, value string }{
+			{"source.name", source.Name},
+			{"source.namespace", source.Namespace},
+			{"source.pod", source.Pod},
+			{"source.container", source.Container},
+			{"source.file", source.File},
+			{"source.stream", source.Stream},
+		} {
+			if field.value != "" {
+				e.writePair(field.key, field.value)
+			}
+		}
+	}
+	if structured.Stack != "" {
+		e.writePair("stack", structured.Stack)
+	}
+	e.buf.Truncate(e.buf.Len() - 1) // trailing space
+	e.buf.WriteByte('\n')
+}
+
+func (e *Emitter) writePair(key, value string) {
+	e.buf.WriteString(key)
+	e.buf.WriteByte('=')
+	if needsQuote(value) {
+		e.buf.WriteString(strconv.Quote(value))
+	} else {
+		e.buf.WriteString(value)
+	}
+	e.buf.WriteByte(' ')
+}
+
+// needsQuote reports whether a logfmt value must be quoted to be decoded back
+func needsQuote(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,35 +1,148 @@
 package stdout
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
+	"github.com/appthrust/kutelog/pkg/core"
 	"github.com/appthrust/kutelog/pkg/entry"
 )
 
+var _ core.Emitter = &Emitter{}
+
+// Format is how entries are written
+type Format string
+
+const (
+	// FormatPretty writes aligned lines with level badges, the time since start, and indented data and stacks
+	FormatPretty Format = "pretty"
+	// FormatLogfmt writes key=value lines with nested data keys joined by dots
+	FormatLogfmt Format = "logfmt"
+	// FormatJSON writes compact JSON lines
+	FormatJSON Format = "json"
+)
+
+// ColorMode decides whether ANSI colors are written
+type ColorMode string
+
+const (
+	// ColorAuto uses colors if the output is a terminal and NO_COLOR is not set
+	ColorAuto ColorMode = "auto"
+	// ColorAlways uses colors even if the output is piped, e.g. to less -R
+	ColorAlways ColorMode = "always"
+	// ColorNever writes plain text
+	ColorNever ColorMode = "never"
+)
+
+// EmitterOptions configures how the emitter writes entries
+type EmitterOptions struct {
+	Output io.Writer // defaults to os.Stdout
+	Format Format    // defaults to FormatJSON
+	Color  ColorMode // defaults to ColorAuto; only used by FormatPretty
+}
+
 // Emitter writes log entries to stdout
+// Unstructured lines are written as they are in every format
 type Emitter struct {
-	encoder *json.Encoder
+	output io.Writer
+	format Format
+	color  bool
+	start  time.Time // origin of the relative timestamps of FormatPretty
+	buf    bytes.Buffer
 }
 
-// NewEmitter creates a new stdout emitter
+// NewEmitter creates a new stdout emitter writing JSON lines
 func NewEmitter() *Emitter {
+	return NewEmitterWithOptions(&EmitterOptions{})
+}
+
+// NewEmitterWithOptions creates a new stdout emitter with the given options
+func NewEmitterWithOptions(options *EmitterOptions) *Emitter {
+	output := options.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	format := options.Format
+	if format == "" {
+		format = FormatJSON
+	}
+	var color bool
+	switch options.Color {
+	case ColorAlways:
+		color = true
+	case ColorNever:
+		color = false
+	default:
+		color = isTerminal(output) && os.Getenv("NO_COLOR") == ""
+	}
 	return &Emitter{
-		encoder: json.NewEncoder(os.Stdout),
+		output: output,
+		format: format,
+		color:  color,
+		start:  time.Now(),
 	}
 }
 
+// ParseFormat validates the name of a format
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatPretty, FormatLogfmt, FormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format: %s", name)
+}
+
+// ParseColorMode validates the name of a color mode
+func ParseColorMode(name string) (ColorMode, error) {
+	switch mode := ColorMode(name); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown color mode: %s", name)
+}
+
+// isTerminal reports whether the output is a character device such as a terminal
+func isTerminal(output io.Writer) bool {
+	file, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // Init initializes the emitter
-func (e *Emitter) Init() error {
+func (e *Emitter) Init(ctx context.Context) error {
+	e.start = time.Now()
 	return nil
 }
 
 // Emit writes the entry to stdout
 func (e *Emitter) Emit(entry *entry.Entry) {
-	if entry.Structured != nil {
-		e.encoder.Encode(entry.Structured)
-	} else if entry.Unstructured != "" {
-		fmt.Fprintln(os.Stdout, entry.Unstructured)
+	if entry == nil || (entry.Structured == nil && entry.Unstructured == "") {
+		return
+	}
+	e.buf.Reset()
+	switch {
+	case e.format == FormatPretty:
+		e.writePretty(entry)
+	case entry.Structured == nil:
+		e.buf.WriteString(entry.Unstructured)
+		e.buf.WriteByte('\n')
+	case e.format == FormatLogfmt:
+		e.writeLogfmt(entry)
+	default:
+		e.writeJSON(entry)
 	}
+	// a single write per entry keeps lines whole if stdout is shared
+	e.output.Write(e.buf.Bytes())
+}
+
+// Close does nothing since stdout is not owned by the emitter
+func (e *Emitter) Close(ctx context.Context) error {
+	return nil
 }
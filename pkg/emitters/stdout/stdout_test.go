@@ -2,9 +2,11 @@ package stdout_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,7 +41,7 @@ var _ = Describe("Stdout Emitter", func() {
 		}()
 
 		emitter = stdout.NewEmitter()
-		Expect(emitter.Init()).To(Succeed())
+		Expect(emitter.Init(context.Background())).To(Succeed())
 	})
 
 	AfterEach(func() {
@@ -94,4 +96,110 @@ var _ = Describe("Stdout Emitter", func() {
 			Expect(output.String()).To(BeEmpty())
 		})
 	})
+
+	Context("with formats", func() {
+		var buf *bytes.Buffer
+
+		newEmitter := func(format stdout.Format, color stdout.ColorMode) *stdout.Emitter {
+			buf = new(bytes.Buffer)
+			e := stdout.NewEmitterWithOptions(&stdout.EmitterOptions{
+				Output: buf,
+				Format: format,
+				Color:  color,
+			})
+			Expect(e.Init(context.Background())).To(Succeed())
+			return e
+		}
+
+		structured := func() *entry.Entry {
+			return &entry.Entry{
+				Structured: &entry.Structured{
+					Timestamp: time.Date(2025, 1, 30, 15, 52, 37, 0, time.UTC),
+					Level:     entry.LevelError,
+					Message:   "reconcile failed",
+					Data: map[string]interface{}{
+						"controller": "machine",
+						"request":    map[string]interface{}{"attempts": 2.0},
+					},
+					Stack: "main.main()\n\t/app/main.go:10",
+				},
+				Source: &entry.Source{Namespace: "default", Pod: "web-0"},
+			}
+		}
+
+		It("writes pretty lines with indented data and stacks", func() {
+			e := newEmitter(stdout.FormatPretty, stdout.ColorNever)
+			e.Emit(structured())
+			lines := strings.Split(buf.String(), "\n")
+			Expect(lines[0]).To(MatchRegexp(`^[+-]\d+:\d{2}:\d{2}\.\d{3} ERROR \[default/web-0\] reconcile failed$`))
+			Expect(lines[1:]).To(Equal([]string{
+				"    controller: machine",
+				`    request: {"attempts":2}`,
+				"    main.main()",
+				"    \t/app/main.go:10",
+				"",
+			}))
+		})
+
+		It("aligns unstructured lines with structured ones", func() {
+			e := newEmitter(stdout.FormatPretty, stdout.ColorNever)
+			e.Emit(&entry.Entry{Unstructured: "plain text log"})
+			Expect(buf.String()).To(MatchRegexp(`^\+\d{2}:\d{2}:\d{2}\.\d{3}       plain text log\n$`))
+		})
+
+		It("shows the time since start", func() {
+			e := newEmitter(stdout.FormatPretty, stdout.ColorNever)
+			e.Emit(&entry.Entry{Structured: &entry.Structured{
+				Timestamp: time.Now().Add(61 * time.Second),
+				Level:     entry.LevelDebug,
+				Verbosity: 2,
+				Message:   "later",
+			}})
+			Expect(buf.String()).To(HavePrefix("+00:01:0"))
+			Expect(buf.String()).To(ContainSubstring(" V2    later"))
+		})
+
+		It("colors pretty lines only when asked", func() {
+			e := newEmitter(stdout.FormatPretty, stdout.ColorAlways)
+			e.Emit(structured())
+			Expect(buf.String()).To(ContainSubstring("\x1b[30;41m ERROR \x1b[0m"))
+
+			// a buffer is not a terminal
+			e = newEmitter(stdout.FormatPretty, stdout.ColorAuto)
+			e.Emit(structured())
+			Expect(buf.String()).NotTo(ContainSubstring("\x1b["))
+		})
+
+		It("writes logfmt lines", func() {
+			e := newEmitter(stdout.FormatLogfmt, stdout.ColorAlways)
+			e.Emit(structured())
+			Expect(buf.String()).To(Equal(`time=2025-01-30T15:52:37Z level=error msg="reconcile failed" controller=machine request.attempts=2 source.namespace=default source.pod=web-0 stack="main.main()\n\t/app/main.go:10"` + "\n"))
+		})
+
+		It("writes JSON lines including the source", func() {
+			e := newEmitter(stdout.FormatJSON, stdout.ColorAlways)
+			e.Emit(structured())
+			Expect(buf.String()).To(HaveSuffix("}\n"))
+			Expect(strings.Count(buf.String(), "\n")).To(Equal(1))
+			var received map[string]interface{}
+			Expect(json.Unmarshal(buf.Bytes(), &received)).To(Succeed())
+			Expect(received).To(HaveKeyWithValue("message", "reconcile failed"))
+			Expect(received).To(HaveKeyWithValue("source", map[string]interface{}{"namespace": "default", "pod": "web-0"}))
+		})
+
+		It("writes unstructured lines as they are in machine formats", func() {
+			for _, format := range []stdout.Format{stdout.FormatLogfmt, stdout.FormatJSON} {
+				e := newEmitter(format, stdout.ColorNever)
+				e.Emit(&entry.Entry{Unstructured: "plain text log"})
+				Expect(buf.String()).To(Equal("plain text log\n"))
+			}
+		})
+
+		It("rejects unknown format names", func() {
+			_, err := stdout.ParseFormat("yaml")
+			Expect(err).To(HaveOccurred())
+			_, err = stdout.ParseColorMode("sometimes")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// TokenParam is the query parameter carrying the access token, as in the URL printed at startup
+	TokenParam = "token"
+	// tokenCookie is the cookie the viewer keeps the token in after the first visit;
+	// the port is part of the name since cookies are shared by all ports of a host
+	tokenCookie = "kutelog-token"
+)
+
+// newToken generates a random access token for a session
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
+
+// Token returns the access token required by every endpoint, empty if authentication is disabled
+func (e *Emitter) Token() string {
+	return e.token
+}
+
+// URL returns the address of the viewer including the access token
+func (e *Emitter) URL() string {
+	if e.token == "" {
+		return e.Address() + "/"
+	}
+	return e.Address() + "/?" + TokenParam + "=" + e.token
+}
+
+// cookieName is the name of the token cookie of this server
+func (e *Emitter) cookieName() string {
+	if _, port, err := net.SplitHostPort(e.addr); err == nil {
+		return tokenCookie + "-" + port
+	}
+	return tokenCookie
+}
+
+// authorize rejects requests from other origins and requests without the access token,
+// which is accepted as the token query parameter, the cookie set by the viewer, or a bearer token
+func (e *Emitter) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !e.checkOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		if e.token == "" {
+			next(w, r)
+			return
+		}
+
+		if token := r.URL.Query().Get(TokenParam); token != "" {
+			if !e.validToken(token) {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			// Keep the token in a cookie so that the viewer and its links work without it in the URL
+			http.SetCookie(w, &http.Cookie{
+				Name:     e.cookieName(),
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   e.tls != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			if r.URL.Path == "/" {
+				// Hide the token from the address bar and the browser history
+				http.Redirect(w, r, "/", http.StatusSeeOther)
+				return
+			}
+			next(w, r)
+			return
+		}
+		if cookie, err := r.Cookie(e.cookieName()); err == nil && e.validToken(cookie.Value) {
+			next(w, r)
+			return
+		}
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && e.validToken(token) {
+			next(w, r)
+			return
+		}
+		http.Error(w, "missing or invalid token: open the URL printed by kutelog", http.StatusUnauthorized)
+	}
+}
+
+func (e *Emitter) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(e.token)) == 1
+}
+
+// checkOrigin allows requests from the viewer itself and from the allowed origins
+// Requests without an Origin header come from clients other than browsers, which always send it for WebSockets
+func (e *Emitter) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range e.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,238 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/history"
+)
+
+// DefaultQueueSize is the default number of messages queued per client
+const DefaultQueueSize = 1024
+
+// writeTimeout is how long a single write to a client may take before the client is disconnected
+const writeTimeout = 10 * time.Second
+
+// SlowClientPolicy decides what happens to a client whose send queue is full
+type SlowClientPolicy string
+
+const (
+	// SlowClientDrop drops messages for the client and tells it how many were dropped once it catches up
+	SlowClientDrop SlowClientPolicy = "drop"
+	// SlowClientDisconnect disconnects the client, which then reconnects and resumes from history
+	SlowClientDisconnect SlowClientPolicy = "disconnect"
+)
+
+// MessageTypeDropped is the type of the notice telling a client that messages were dropped
+const MessageTypeDropped = "dropped"
+
+// queuedMessage is a broadcast message waiting to be written to a client
+type queuedMessage struct {
+	id    int64
+	data  []byte
+	entry *entry.Entry // for matching the subscription of the client
+}
+
+// subscribeRequest is a subscription read from a client, handed to its writer
+type subscribeRequest struct {
+	filter *filter
+	after  int64
+	err    error // set if the subscription is invalid
+}
+
+// client is a connected WebSocket client
+// Only its writer goroutine writes to conn, so that replay and broadcast never write concurrently
+type client struct {
+	conn      *websocket.Conn
+	queue     chan queuedMessage
+	requests  chan subscribeRequest
+	filter    atomic.Pointer[filter] // current subscription, nil for all messages
+	dropped   atomic.Int64           // messages dropped since the last notice
+	done      chan struct{}
+	closeOnce sync.Once
+	draining  chan struct{} // closed by drain to write the queued messages and stop
+	drainOnce sync.Once
+	stopped   chan struct{} // closed when the writer returns
+}
+
+func newClient(conn *websocket.Conn, queueSize int) *client {
+	return &client{
+		conn:     conn,
+		queue:    make(chan queuedMessage, queueSize),
+		requests: make(chan subscribeRequest),
+		done:     make(chan struct{}),
+		draining: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// enqueue queues a message matching the subscription without blocking,
+// applying policy if the queue is full
+func (c *client) enqueue(message queuedMessage, policy SlowClientPolicy) {
+	if !c.filter.Load().matches(message.entry) {
+		return
+	}
+	select {
+	case c.queue <- message:
+	default:
+		if policy == SlowClientDisconnect {
+			c.close()
+			return
+		}
+		c.dropped.Add(1)
+	}
+}
+
+// write writes a single message, giving up after writeTimeout
+func (c *client) write(data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// writeLoop replays the history newer than after, then writes queued messages until the client is closed
+// or drained
+func (c *client) writeLoop(messageHistory history.Store, after int64) {
+	defer close(c.stopped)
+	defer c.close()
+
+	lastID, err := c.replay(messageHistory, after)
+	if err != nil {
+		return
+	}
+
+	for {
+		select {
+		case message := <-c.queue:
+			if lastID, err = c.deliver(message, lastID); err != nil {
+				return
+			}
+			// Tell the client about dropped messages once it has caught up
+			if len(c.queue) == 0 {
+				if dropped := c.dropped.Swap(0); dropped > 0 {
+					if err := c.writeNotice(Message{Type: MessageTypeDropped, Dropped: dropped}); err != nil {
+						return
+					}
+				}
+			}
+		case request := <-c.requests:
+			if request.err != nil {
+				if err := c.writeNotice(Message{Type: MessageTypeError, Error: request.err.Error()}); err != nil {
+					return
+				}
+				continue
+			}
+			c.filter.Store(request.filter)
+			if err := c.writeNotice(Message{Type: MessageTypeSubscribed}); err != nil {
+				return
+			}
+			if lastID, err = c.replay(messageHistory, request.after); err != nil {
+				return
+			}
+		case <-c.draining:
+			for {
+				select {
+				case message := <-c.queue:
+					if lastID, err = c.deliver(message, lastID); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// deliver writes a queued message, returning the ID of the last message written
+func (c *client) deliver(message queuedMessage, lastID int64) (int64, error) {
+	// Messages broadcast during the replay may have been replayed already,
+	// and the subscription may have changed since the message was queued
+	if message.id <= lastID || !c.filter.Load().matches(message.entry) {
+		return lastID, nil
+	}
+	if err := c.write(message.data); err != nil {
+		return lastID, err
+	}
+	return message.id, nil
+}
+
+// replay writes the history newer than after that matches the subscription,
+// returning the ID of the last message in the history
+func (c *client) replay(messageHistory history.Store, after int64) (int64, error) {
+	current := c.filter.Load()
+	lastID := after
+	var writeErr error
+	err := messageHistory.Replay(after, func(record history.Record) bool {
+		lastID = record.ID
+		if current != nil {
+			e, err := decodeEntry(record.Data)
+			if err != nil || !current.matches(e) {
+				return true
+			}
+		}
+		writeErr = c.write(record.Data)
+		return writeErr == nil
+	})
+	if err != nil {
+		return lastID, err
+	}
+	return lastID, writeErr
+}
+
+// writeNotice writes a message that is not a log message
+func (c *client) writeNotice(notice Message) error {
+	data, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+// readLoop reads subscriptions until the client disconnects
+func (c *client) readLoop() {
+	defer c.close()
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var request subscribeRequest
+		var subscription Subscription
+		if err := json.Unmarshal(data, &subscription); err != nil {
+			request.err = fmt.Errorf("invalid message: %w", err)
+		} else if subscription.Type != MessageTypeSubscribe {
+			request.err = fmt.Errorf("unknown message type: %q", subscription.Type)
+		} else {
+			request.filter, request.err = compileFilter(subscription.Filter)
+			request.after = subscription.After
+		}
+		select {
+		case c.requests <- request:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// drain makes the writer write the messages queued so far and stop, after which the client is closed
+func (c *client) drain() {
+	c.drainOnce.Do(func() {
+		close(c.draining)
+	})
+}
+
+// close disconnects the client, stopping its goroutines
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
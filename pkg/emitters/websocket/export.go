@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/history"
+	"github.com/appthrust/kutelog/pkg/query"
+)
+
+// Formats of /export
+const (
+	// ExportJSONL exports entries as JSON lines in the format of the file emitter, replayable with kutelog replay
+	ExportJSONL = "jsonl"
+	// ExportCSV exports one row per entry with a data.<key> column per data key, nested keys joined by dots
+	ExportCSV = "csv"
+	// ExportRaw exports the lines as they were read
+	ExportRaw = "raw"
+)
+
+// exportRequest is a parsed /export request
+type exportRequest struct {
+	format      string
+	since       time.Time // zero for no lower bound
+	until       time.Time // zero for no upper bound
+	minSeverity int       // -1 for all levels
+	query       *query.Query
+}
+
+// parseExportRequest reads the query parameters of /export:
+// format (jsonl, csv or raw), since and until (RFC 3339 times or durations before now, e.g. 15m),
+// level (minimum level) and query (a filter expression)
+func parseExportRequest(r *http.Request, now time.Time) (*exportRequest, error) {
+	params := r.URL.Query()
+	request := &exportRequest{format: params.Get("format"), minSeverity: -1}
+	switch request.format {
+	case "":
+		request.format = ExportJSONL
+	case ExportJSONL, ExportCSV, ExportRaw:
+	default:
+		return nil, fmt.Errorf("unknown format: %s", request.format)
+	}
+
+	var err error
+	if request.since, err = parseExportTime(params.Get("since"), now); err != nil {
+		return nil, fmt.Errorf("invalid since: %w", err)
+	}
+	if request.until, err = parseExportTime(params.Get("until"), now); err != nil {
+		return nil, fmt.Errorf("invalid until: %w", err)
+	}
+	if value := params.Get("level"); value != "" {
+		level, err := entry.ParseLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level: %w", err)
+		}
+		request.minSeverity = level.Severity()
+	}
+	if value := params.Get("query"); value != "" {
+		if request.query, err = query.Compile(value); err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+	}
+	return request, nil
+}
+
+// parseExportTime parses an RFC 3339 time or a duration before now
+func parseExportTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// matches reports whether the entry received at the given time is exported
+func (r *exportRequest) matches(e *entry.Entry, received time.Time) bool {
+	timestamp := received
+	severity := entry.LevelInfo.Severity()
+	if e.Structured != nil {
+		timestamp = e.Structured.Timestamp
+		severity = e.Structured.Level.Severity()
+	}
+	if !r.since.IsZero() && timestamp.Before(r.since) {
+		return false
+	}
+	if !r.until.IsZero() && timestamp.After(r.until) {
+		return false
+	}
+	return severity >= r.minSeverity && r.query.Match(e)
+}
+
+// receivedAt returns the time a message was emitted, which is kept in the upper bits of its ID
+func receivedAt(id int64) time.Time {
+	return time.UnixMilli(id >> 12)
+}
+
+// handleExport serves the message history as a file download
+func (e *Emitter) handleExport(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	request, err := parseExportRequest(r, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ext := map[string]string{ExportJSONL: "jsonl", ExportCSV: "csv", ExportRaw: "log"}[request.format]
+	contentType := map[string]string{
+		ExportJSONL: "application/x-ndjson",
+		ExportCSV:   "text/csv; charset=utf-8",
+		ExportRaw:   "text/plain; charset=utf-8",
+	}[request.format]
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="kutelog-%s.%s"`, now.Format("20060102-150405"), ext))
+
+	// entries are written as they are replayed, so that the history is never held in memory
+	out := &exportWriter{ResponseWriter: w}
+	switch request.format {
+	case ExportCSV:
+		err = e.writeCSV(out, request)
+	case ExportRaw:
+		err = e.exportEach(request, math.MaxInt64, func(en *entry.Entry, _ int64) {
+			fmt.Fprintln(out, rawText(en))
+		})
+	default:
+		encoder := json.NewEncoder(out)
+		encoder.SetEscapeHTML(false)
+		err = e.exportEach(request, math.MaxInt64, func(en *entry.Entry, _ int64) {
+			encoder.Encode(en)
+		})
+	}
+	if err != nil {
+		if out.started {
+			// the download is cut short; the status has already been sent
+			fmt.Fprintf(os.Stderr, "failed to export history: %v\n", err)
+			return
+		}
+		w.Header().Del("Content-Disposition")
+		http.Error(w, fmt.Sprintf("failed to read history: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// exportWriter remembers whether the download has started, after which errors cannot change the status
+type exportWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// exportEach calls fn for every exported entry in history up to the given ID, decoding one record at a time
+func (e *Emitter) exportEach(request *exportRequest, until int64, fn func(en *entry.Entry, id int64)) error {
+	return e.messageHistory.Replay(0, func(record history.Record) bool {
+		if record.ID > until {
+			return false
+		}
+		en, err := decodeEntry(record.Data)
+		if err == nil && request.matches(en, receivedAt(record.ID)) {
+			en.Raw = record.Raw
+			fn(en, record.ID)
+		}
+		return true
+	})
+}
+
+// rawText returns the lines an entry was parsed from, or its message if they are unknown
+func rawText(e *entry.Entry) string {
+	if e.Structured == nil {
+		return e.Unstructured
+	}
+	if e.Raw != "" {
+		return e.Raw
+	}
+	return e.Structured.Message
+}
+
+// writeCSV writes a header and a row per entry, with a column for every data key of any entry
+// The history is replayed twice: once to collect the data keys and once to write the rows
+func (e *Emitter) writeCSV(w io.Writer, request *exportRequest) error {
+	keys := make(map[string]bool)
+	last := int64(-1) // ID of the last exported entry, so that entries emitted meanwhile are left out
+	err := e.exportEach(request, math.MaxInt64, func(en *entry.Entry, id int64) {
+		if en.Structured != nil {
+			for key := range en.Structured.FlatData() {
+				keys[key] = true
+			}
+		}
+		last = id
+	})
+	if err != nil {
+		return err
+	}
+	dataKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		dataKeys = append(dataKeys, key)
+	}
+	sort.Strings(dataKeys)
+
+	header := []string{"timestamp", "level", "message", "source"}
+	for _, key := range dataKeys {
+		// prefixed so that data keys such as message do not repeat the other columns
+		header = append(header, "data."+key)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(append(header, "stack"))
+	err = e.exportEach(request, last, func(en *entry.Entry, _ int64) {
+		record := make([]string, 0, len(dataKeys)+5)
+		var row map[string]interface{}
+		if en.Structured != nil {
+			record = append(record, en.Structured.Timestamp.Format(time.RFC3339Nano), string(en.Structured.Level), en.Structured.Message)
+			row = en.Structured.FlatData()
+		} else {
+			record = append(record, "", "", en.Unstructured)
+		}
+		record = append(record, en.Source.String())
+		for _, key := range dataKeys {
+			value, ok := row[key]
+			if !ok {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, entry.FormatValue(value))
+		}
+		if en.Structured != nil {
+			record = append(record, en.Structured.Stack)
+		} else {
+			record = append(record, "")
+		}
+		writer.Write(record)
+	})
+	writer.Flush()
+	return err
+}
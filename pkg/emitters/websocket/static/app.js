@@ -64,6 +64,10 @@ document.addEventListener("DOMContentLoaded", () => {
 let ws = null;
 let reconnectAttempt = 0;
 let lastReceivedTimestamp = 0;
+// Filter applied by the server, or null to receive everything
+let subscription = null;
+// Whether logs sent before the server acknowledged the subscription are ignored
+let awaitingSubscribed = false;
 const INITIAL_RETRY_DELAY = 1000; // Start with 1 second delay
 
 // Connection status indicator
@@ -84,9 +88,12 @@ function updateConnectionStatus(isConnected) {
 
 const TIMESTAMP_STYLE = "color: #888";
 const LEVEL_STYLES = {
+	trace: "color: #6b7280",
 	info: "color: #3b82f6",
 	warning: "color: #f59e0b",
 	error: "color: #ef4444",
+	panic: "color: #ef4444; font-weight: bold",
+	fatal: "color: #ef4444; font-weight: bold",
 	debug: "color: #10b981",
 };
 const MESSAGE_STYLE = "color: inherit";
@@ -136,25 +143,196 @@ const logFn = {
 		console.debug(...args);
 	},
 };
+// Levels without a console method of their own are mapped to the nearest one
+logFn.trace = logFn.debug;
+logFn.panic = logFn.error;
+logFn.fatal = logFn.error;
+
+// Format level label, including logr V-level for verbose debug logs
+function formatLevel(data) {
+	if (typeof data.verbosity === "number" && data.verbosity > 0) {
+		return `${data.level}(${data.verbosity})`;
+	}
+	return data.level;
+}
+
+// Badge colors of sources, picked by a hash of the source label
+const SOURCE_COLORS = [
+	"#8b5cf6",
+	"#ec4899",
+	"#14b8a6",
+	"#f97316",
+	"#84cc16",
+	"#06b6d4",
+];
+
+// Format source label, e.g. default/app-1:manager or make:stderr
+function formatSource(source) {
+	if (typeof source !== "object" || source === null) {
+		return undefined;
+	}
+	const pod =
+		source.namespace && source.pod
+			? `${source.namespace}/${source.pod}`
+			: source.pod;
+	const label = [source.name, pod, source.container, source.file, source.stream]
+		.filter((part) => part)
+		.join(":");
+	return label || undefined;
+}
+
+function sourceStyle(label) {
+	let hash = 0;
+	for (const char of label) {
+		hash = (hash * 31 + char.codePointAt(0)) | 0;
+	}
+	const color = SOURCE_COLORS[Math.abs(hash) % SOURCE_COLORS.length];
+	return `background: ${color}; color: #fff; border-radius: 3px; padding: 0 4px`;
+}
+
+// Console format prefix showing the source as a badge
+function sourcePrefix(label) {
+	return label === undefined ? "" : `%c${label}%c `;
+}
+
+// Styles for the placeholders of sourcePrefix
+function sourceStyles(label) {
+	return label === undefined ? [] : [sourceStyle(label), ""];
+}
+
+// Sources seen so far and whether their logs are shown
+const sourceFilters = new Map();
+
+// Register source in the filter list and return whether its logs are shown
+function isSourceEnabled(label) {
+	if (!sourceFilters.has(label)) {
+		sourceFilters.set(label, true);
+		addSourceFilter(label);
+	}
+	return sourceFilters.get(label);
+}
+
+function addSourceFilter(label) {
+	const container = document.getElementById("source-filter");
+	const list = document.getElementById("source-list");
+	if (!container || !list) return;
+	container.classList.remove("hidden");
+
+	const item = document.createElement("label");
+	item.className = "flex items-center gap-2 cursor-pointer";
+	const checkbox = document.createElement("input");
+	checkbox.type = "checkbox";
+	checkbox.checked = true;
+	checkbox.addEventListener("change", () => {
+		sourceFilters.set(label, checkbox.checked);
+	});
+	const badge = document.createElement("span");
+	badge.textContent = label;
+	badge.style.cssText = sourceStyle(label);
+	item.append(checkbox, badge);
+	list.append(item);
+}
+
+// Parse "key=value key2=value2" into the data predicates of a filter
+function parseDataFilter(text) {
+	const data = {};
+	for (const pair of text.split(/\s+/)) {
+		const index = pair.indexOf("=");
+		if (index > 0) {
+			data[pair.slice(0, index)] = pair.slice(index + 1);
+		}
+	}
+	return data;
+}
+
+// Ask the server to deliver only matching logs, replaying history after the given ID
+function subscribe(after) {
+	if (ws === null || ws.readyState !== WebSocket.OPEN) return;
+	awaitingSubscribed = true;
+	ws.send(JSON.stringify({ type: "subscribe", after, filter: subscription }));
+}
+
+function showFilterError(error) {
+	const element = document.getElementById("filter-error");
+	if (!element) return;
+	element.textContent = error;
+	element.classList.toggle("hidden", error === "");
+}
+
+document
+	.getElementById("subscription-form")
+	?.addEventListener("submit", (event) => {
+		event.preventDefault();
+		subscription = {
+			level: document.getElementById("filter-level").value,
+			message: document.getElementById("filter-message").value,
+			data: parseDataFilter(document.getElementById("filter-data").value),
+			query: document.getElementById("filter-query").value,
+		};
+		showFilterError("");
+		// Start over with the history matching the new filter
+		console.clear();
+		for (const type of Object.keys(logCounts)) {
+			logCounts[type] = 0;
+			updateCounter(type);
+		}
+		lastReceivedTimestamp = 0;
+		subscribe(0);
+	});
 
 function connect() {
-	ws = new WebSocket(`ws://${location.host}/ws`);
+	// Ask the server to replay only messages newer than the last one received
+	// With a filter, nothing is replayed until the subscription is sent
+	const after =
+		subscription === null ? lastReceivedTimestamp : Number.MAX_SAFE_INTEGER;
+	const scheme = location.protocol === "https:" ? "wss" : "ws";
+	ws = new WebSocket(`${scheme}://${location.host}/ws?after=${after}`);
 
 	ws.onopen = () => {
 		console.log("Connected to WebSocket server");
 		reconnectAttempt = 0;
 		updateConnectionStatus(true);
+		if (subscription !== null) {
+			subscribe(lastReceivedTimestamp);
+		}
 	};
 
 	ws.onmessage = (event) => {
 		try {
 			const message = JSON.parse(event.data);
+			// Notice from the server that messages were dropped because the browser fell behind
+			if (message.type === "dropped") {
+				console.warn(
+					`Kutelog: ${message.dropped} messages were dropped because the browser could not keep up. Reload the page to replay them from history.`,
+				);
+				return;
+			}
+			if (message.type === "subscribed") {
+				awaitingSubscribed = false;
+				return;
+			}
+			// The server rejected the filter and keeps the previous one
+			if (message.type === "error") {
+				awaitingSubscribed = false;
+				showFilterError(message.error);
+				return;
+			}
+			// Skip logs sent before the server applied the filter
+			if (awaitingSubscribed) {
+				return;
+			}
 			// Skip if message is already received or older
 			if (message.id <= lastReceivedTimestamp) {
 				return;
 			}
 			lastReceivedTimestamp = message.id;
 
+			// Skip logs of sources unchecked in the source filter
+			const source = formatSource(message.source);
+			if (source !== undefined && !isSourceEnabled(source)) {
+				return;
+			}
+
 			const data = message.body;
 			if (
 				typeof data === "object" &&
@@ -174,9 +352,10 @@ function connect() {
 				} catch {
 					formattedTimestamp = data.timestamp;
 				}
-				const msg = `%c${formattedTimestamp}%c ${data.level} %c${data.message}`;
+				const msg = `${sourcePrefix(source)}%c${formattedTimestamp}%c ${formatLevel(data)} %c${data.message}`;
 				const args = [
 					msg,
+					...sourceStyles(source),
 					TIMESTAMP_STYLE,
 					LEVEL_STYLES[data.level] ?? "color: inherit",
 					MESSAGE_STYLE,
@@ -184,7 +363,9 @@ function connect() {
 				if (data.data !== undefined) {
 					args.push(data.data);
 				}
-				if (data.stack !== undefined) {
+				if (Array.isArray(message.frames) && message.frames.length > 0) {
+					args.push(formatFrames(message.frames));
+				} else if (data.stack !== undefined) {
 					args.push(data.stack);
 				}
 				const fn = logFn[data.level] ?? console.log;
@@ -196,7 +377,11 @@ function connect() {
 			} else if (typeof data === "string") {
 				logCounts.log++;
 				updateCounter("log");
-				console.log(data);
+				if (source !== undefined) {
+					console.log(`${sourcePrefix(source)}%s`, ...sourceStyles(source), data);
+				} else {
+					console.log(data);
+				}
 			} else {
 				logCounts.log++;
 				updateCounter("log");
@@ -218,6 +403,26 @@ function connect() {
 	};
 }
 
+// Format parsed stack frames, showing editor links in place of file paths where available
+function formatFrames(frames) {
+	const lines = [];
+	let goroutine;
+	for (const frame of frames) {
+		if (frame.goroutine !== undefined && frame.goroutine !== goroutine) {
+			goroutine = frame.goroutine;
+			lines.push(`goroutine ${goroutine}:`);
+		}
+		const location =
+			frame.url ??
+			(frame.file !== undefined ? `${frame.file}:${frame.line ?? 0}` : "");
+		lines.push(frame.function ?? "?");
+		if (location !== "") {
+			lines.push(`\t${location}`);
+		}
+	}
+	return lines.join("\n");
+}
+
 function reconnect() {
 	const retryDelay = INITIAL_RETRY_DELAY;
 	setTimeout(() => {
@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/query"
+)
+
+// Types of messages exchanged with clients besides log messages
+const (
+	// MessageTypeSubscribe is sent by clients to receive only messages matching a filter
+	MessageTypeSubscribe = "subscribe"
+	// MessageTypeSubscribed acknowledges a subscription before the matching history is replayed
+	MessageTypeSubscribed = "subscribed"
+	// MessageTypeError reports an invalid request from the client
+	MessageTypeError = "error"
+)
+
+// Subscription is sent by a client to receive only the messages matching Filter
+// The history after After is replayed with the new filter, so clients typically
+// clear their view and subscribe with After set to 0
+type Subscription struct {
+	Type   string `json:"type"` // MessageTypeSubscribe
+	After  int64  `json:"after"`
+	Filter Filter `json:"filter"`
+}
+
+// Filter selects messages delivered to a client; empty fields match everything
+type Filter struct {
+	// Level is the minimum level, e.g. warning matches warning, error, panic and fatal
+	// Unstructured messages rank as info
+	Level entry.Level `json:"level,omitempty"`
+	// Message is a regular expression matched against the message or unstructured text
+	Message string `json:"message,omitempty"`
+	// Data requires each key to have the given value; keys may be dotted paths into nested objects
+	// Numbers match by value, e.g. "1000000" matches 1e6, other values by their JSON text
+	Data map[string]string `json:"data,omitempty"`
+	// Source requires each non-empty field to be equal to that of the message source
+	Source *entry.Source `json:"source,omitempty"`
+	// Query is a filter expression as accepted by kutelog -filter, e.g. level >= warning && data.controller == "machine"
+	Query string `json:"query,omitempty"`
+}
+
+// filter is a compiled Filter
+type filter struct {
+	minSeverity int // -1 for no level filter
+	message     *regexp.Regexp
+	data        map[string]string
+	source      *entry.Source
+	query       *query.Query
+}
+
+// compileFilter validates a Filter received from a client
+func compileFilter(f Filter) (*filter, error) {
+	compiled := &filter{
+		minSeverity: -1,
+		data:        f.Data,
+		source:      f.Source,
+	}
+	if f.Level != "" {
+		level, err := entry.ParseLevel(string(f.Level))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level: %w", err)
+		}
+		compiled.minSeverity = level.Severity()
+	}
+	if f.Message != "" {
+		re, err := regexp.Compile(f.Message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message pattern: %w", err)
+		}
+		compiled.message = re
+	}
+	if f.Query != "" {
+		q, err := query.Compile(f.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+		compiled.query = q
+	}
+	return compiled, nil
+}
+
+// matches reports whether the entry passes the filter; a nil filter matches everything
+func (f *filter) matches(e *entry.Entry) bool {
+	if f == nil {
+		return true
+	}
+	severity := entry.LevelInfo.Severity()
+	text := e.Unstructured
+	var data map[string]interface{}
+	if e.Structured != nil {
+		severity = e.Structured.Level.Severity()
+		text = e.Structured.Message
+		data = e.Structured.Data
+	}
+	if severity < f.minSeverity {
+		return false
+	}
+	if f.message != nil && !f.message.MatchString(text) {
+		return false
+	}
+	for key, expected := range f.data {
+		value, ok := query.Lookup(data, key)
+		if !ok || !matchesValue(value, expected) {
+			return false
+		}
+	}
+	if f.source != nil && !matchesSource(f.source, e.Source) {
+		return false
+	}
+	return f.query.Match(e)
+}
+
+// matchesValue reports whether a data value equals the text of a data predicate
+// Numbers are compared numerically, as in queries, so that 1000000 matches the 1e+06 of a float
+func matchesValue(value interface{}, expected string) bool {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case int:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	default:
+		return entry.FormatValue(value) == expected
+	}
+	parsed, err := strconv.ParseFloat(expected, 64)
+	return err == nil && parsed == number
+}
+
+// matchesSource reports whether every field set in want equals that of source
+func matchesSource(want, source *entry.Source) bool {
+	if source == nil {
+		source = &entry.Source{}
+	}
+	return (want.Name == "" || want.Name == source.Name) &&
+		(want.Namespace == "" || want.Namespace == source.Namespace) &&
+		(want.Pod == "" || want.Pod == source.Pod) &&
+		(want.Container == "" || want.Container == source.Container) &&
+		(want.File == "" || want.File == source.File) &&
+		(want.Stream == "" || want.Stream == source.Stream)
+}
+
+// decodeEntry restores the entry of a message stored in the history for filtering and exporting
+// The raw lines are kept beside the message in history.Record
+func decodeEntry(data []byte) (*entry.Entry, error) {
+	var msg struct {
+		Body   json.RawMessage `json:"body"`
+		Source *entry.Source   `json:"source"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	e := &entry.Entry{Source: msg.Source}
+	if len(msg.Body) > 0 && msg.Body[0] == '"' {
+		if err := json.Unmarshal(msg.Body, &e.Unstructured); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	e.Structured = &entry.Structured{}
+	if err := json.Unmarshal(msg.Body, e.Structured); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/version"
+)
+
+const (
+	certFileName = "cert.pem"
+	keyFileName  = "key.pem"
+	// certValidity is how long a generated certificate is valid
+	certValidity = 365 * 24 * time.Hour
+	// certRenewBefore is how long before expiry a generated certificate is replaced
+	certRenewBefore = 24 * time.Hour
+)
+
+// TLSOptions configures HTTPS and secure WebSockets
+type TLSOptions struct {
+	// CertFile and KeyFile are PEM files of the certificate to serve
+	// Leave empty to use a self-signed certificate generated on first use
+	CertFile string
+	KeyFile  string
+	// Dir is where the self-signed certificate is kept; defaults to DefaultCertDir
+	Dir string
+}
+
+// DefaultCertDir returns the directory of the self-signed certificate, e.g. ~/.config/kutelog
+func DefaultCertDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, version.Name), nil
+}
+
+// config loads the certificate, generating a self-signed one valid for host if none is configured
+func (o *TLSOptions) config(host string) (*tls.Config, error) {
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+	}
+
+	dir := o.Dir
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCertDir(); err != nil {
+			return nil, fmt.Errorf("failed to find the certificate directory: %w", err)
+		}
+	}
+	cert, err := loadOrCreateSelfSigned(dir, certHosts(host))
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// certHosts returns the names a self-signed certificate is valid for
+func certHosts(host string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// loadOrCreateSelfSigned reuses the certificate in dir if it is valid for all hosts,
+// and generates a new one otherwise
+func loadOrCreateSelfSigned(dir string, hosts []string) (tls.Certificate, error) {
+	certPath := filepath.Join(dir, certFileName)
+	keyPath := filepath.Join(dir, keyFileName)
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil && coversHosts(cert, hosts) {
+		return cert, nil
+	}
+
+	certPEM, keyPEM, err := generateSelfSigned(hosts)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate a TLS certificate: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create the certificate directory: %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to write the TLS key: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to write the TLS certificate: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Generated a self-signed certificate at %s\n", certPath)
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// coversHosts reports whether the certificate is valid for a while and for all hosts
+func coversHosts(cert tls.Certificate, hosts []string) bool {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || time.Until(leaf.NotAfter) < certRenewBefore {
+		return false
+	}
+	for _, host := range hosts {
+		if leaf.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// generateSelfSigned creates a PEM encoded certificate and key for hosts
+func generateSelfSigned(hosts []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{version.Name}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
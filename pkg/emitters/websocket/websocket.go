@@ -1,20 +1,25 @@
 package websocket
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/appthrust/kutelog/pkg/core"
 	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/history"
+	"github.com/appthrust/kutelog/pkg/stack"
 	"github.com/appthrust/kutelog/pkg/version"
 	"github.com/gorilla/websocket"
 )
@@ -22,44 +27,125 @@ import (
 //go:embed static/dist/*
 var staticFiles embed.FS
 
-// DefaultPort is the default port for the WebSocket server
-const DefaultPort = 9106
+const (
+	// DefaultHost is the default address the WebSocket server listens on
+	DefaultHost = "127.0.0.1"
+	// DefaultPort is the first port tried by the WebSocket server
+	DefaultPort = 9106
+)
 
 var _ core.Emitter = &Emitter{}
 
 // Emitter implements WebSocket server that broadcasts log entries to connected clients
 // Message represents a WebSocket message with ID
 type Message struct {
-	ID   int64       `json:"id"` // Combination of timestamp and sequence number (see Emitter.sequence for details)
-	Body interface{} `json:"body"`
+	ID      int64         `json:"id"`             // Combination of timestamp and sequence number (see Emitter.Emit for details)
+	Type    string        `json:"type,omitempty"` // Empty for log messages, otherwise one of the MessageType constants
+	Body    interface{}   `json:"body,omitempty"`
+	Source  *entry.Source `json:"source,omitempty"`  // Origin of the entry, omitted for the default input
+	Dropped int64         `json:"dropped,omitempty"` // Number of messages dropped for a slow client
+	Error   string        `json:"error,omitempty"`   // Reason of a MessageTypeError notice
+	Frames  []stack.Frame `json:"frames,omitempty"`  // Parsed stack of a structured log message
 }
 
 type Emitter struct {
 	server         *http.Server
 	upgrader       websocket.Upgrader
-	clients        sync.Map     // map[*websocket.Conn]struct{}
-	addr           string       // server address for testing
-	messageHistory []Message    // stores message history for replay
-	historyMutex   sync.RWMutex // synchronizes access to message history
-
-	// Sequence number (12 bits, 0-4095)
-	// Due to JavaScript Number type's 53-bit precision limitation, we use the following bit allocation:
-	// - 41 bits: Unix timestamp in milliseconds (supports dates until year 2286)
-	// - 12 bits: Sequence number (allows unique identification of up to 4,096 messages per millisecond)
-	// Total: 53 bits, ensuring the value stays within Number.MAX_SAFE_INTEGER (2^53-1) in JavaScript
-	sequence atomic.Int64
+	clients        sync.Map      // map[*client]struct{}
+	addr           string        // server address for testing
+	messageHistory history.Store // stores message history for replay
+	historyMutex   sync.Mutex    // serializes ID generation and history appends
+	lastID         int64         // ID of the last message, guarded by historyMutex
+	queueSize      int
+	slowClient     SlowClientPolicy
+	host           string
+	port           int
+	socket         string
+	tls            *TLSOptions
+	token          string   // access token required by every endpoint; empty if disabled
+	allowedOrigins []string // origins allowed besides the viewer itself
+	linker         *stack.Linker
+}
+
+// EmitterOptions configures a WebSocket emitter
+type EmitterOptions struct {
+	// History stores messages replayed to newly connected clients
+	// Defaults to an in-memory ring buffer holding history.DefaultMaxEntries messages
+	History history.Store
+	// QueueSize is the number of messages queued per client before SlowClient applies
+	// Defaults to DefaultQueueSize
+	QueueSize int
+	// SlowClient decides what happens to clients that cannot keep up, defaults to SlowClientDrop
+	SlowClient SlowClientPolicy
+	// Host is the address to listen on, e.g. 0.0.0.0 or ::1; defaults to DefaultHost
+	Host string
+	// Port is the port to listen on, failing if it is in use
+	// 0 tries DefaultPort and the following ports until one is free
+	Port int
+	// Socket is the path of a Unix socket to listen on instead of Host and Port
+	Socket string
+	// TLS serves HTTPS and secure WebSockets if set
+	TLS *TLSOptions
+	// Token is the access token required by every endpoint; defaults to a random token per emitter
+	Token string
+	// DisableAuth serves every endpoint without a token
+	DisableAuth bool
+	// AllowedOrigins are origins of other web pages allowed to connect, e.g. http://localhost:3000
+	// "*" allows any origin; by default only the viewer itself may connect
+	AllowedOrigins []string
+	// Linker sets the editor URLs of stack frames; nil sends frames without URLs
+	Linker *stack.Linker
 }
 
-// NewEmitter creates a new WebSocket emitter
+// NewEmitter creates a new WebSocket emitter with default options
 func NewEmitter() *Emitter {
+	return NewEmitterWithOptions(&EmitterOptions{})
+}
+
+// NewEmitterWithOptions creates a new WebSocket emitter with the given options
+func NewEmitterWithOptions(options *EmitterOptions) *Emitter {
+	messageHistory := options.History
+	if messageHistory == nil {
+		messageHistory = history.NewRing(&history.RingOptions{
+			MaxEntries: history.DefaultMaxEntries,
+		})
+	}
+	queueSize := options.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	slowClient := options.SlowClient
+	if slowClient == "" {
+		slowClient = SlowClientDrop
+	}
+	host := options.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	token := options.Token
+	if options.DisableAuth {
+		token = ""
+	} else if token == "" {
+		token = newToken()
+	}
 	return &Emitter{
 		upgrader: websocket.Upgrader{
+			// Origins are checked by authorize before upgrading
 			CheckOrigin: func(r *http.Request) bool {
-				return true // allow all origins for development
+				return true
 			},
 		},
-		clients:        sync.Map{},         // sync.Map is a zero value, no need to initialize
-		messageHistory: make([]Message, 0), // initialize message history
+		clients:        sync.Map{}, // sync.Map is a zero value, no need to initialize
+		messageHistory: messageHistory,
+		queueSize:      queueSize,
+		slowClient:     slowClient,
+		host:           host,
+		port:           options.Port,
+		socket:         options.Socket,
+		tls:            options.TLS,
+		token:          token,
+		allowedOrigins: options.AllowedOrigins,
+		linker:         options.Linker,
 	}
 }
 
@@ -73,84 +159,120 @@ func isPortInUseError(err error) bool {
 	return false
 }
 
-// Init starts WebSocket server on port 9106 or the next available port
-func (e *Emitter) Init() error {
-	// Try to listen on default port first
-	port := DefaultPort
-	var listener net.Listener
-	var err error
-
-	// Try ports until we find an available one
-	for {
-		listener, err = net.Listen("tcp4", fmt.Sprintf("127.0.0.1:%d", port))
-		if err == nil {
-			break
-		}
-		if !isPortInUseError(err) {
-			return fmt.Errorf("failed to listen: %w", err)
+// Init starts the WebSocket server on the configured address
+func (e *Emitter) Init(ctx context.Context) error {
+	listener, err := e.listen(ctx)
+	if err != nil {
+		return err
+	}
+	if e.tls != nil {
+		config, err := e.tls.config(e.host)
+		if err != nil {
+			listener.Close()
+			return err
 		}
-		port++
+		listener = tls.NewListener(listener, config)
 	}
-
 	e.addr = listener.Addr().String()
 
 	// Setup routes
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", e.handleWS)
-	mux.HandleFunc("/", e.handleIndex)
-	mux.HandleFunc("/version", e.handleVersion)
+	mux.HandleFunc("/ws", e.authorize(e.handleWS))
+	mux.HandleFunc("/", e.authorize(e.handleIndex))
+	mux.HandleFunc("/version", e.authorize(e.handleVersion))
+	mux.HandleFunc("/export", e.authorize(e.handleExport))
 
 	// Start server
 	e.server = &http.Server{
 		Handler: mux,
 	}
-	fmt.Printf("WebSocket server listening on http://%s\n", e.addr)
+	fmt.Fprintf(os.Stderr, "WebSocket server listening on %s\n", e.URL())
 	go e.server.Serve(listener)
 	return nil
 }
 
-// Address returns server address (for testing)
+// listen listens on the Unix socket, the fixed port, or the first free port from DefaultPort
+func (e *Emitter) listen(ctx context.Context) (net.Listener, error) {
+	var listenConfig net.ListenConfig
+	if e.socket != "" {
+		if info, err := os.Stat(e.socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			// only a socket file left behind by a previous run that was killed refuses connections;
+			// one still served, e.g. by another kutelog, is kept
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "unix", e.socket)
+			if err == nil {
+				conn.Close()
+			}
+			if !errors.Is(err, syscall.ECONNREFUSED) {
+				return nil, fmt.Errorf("failed to listen: address %s already in use", e.socket)
+			}
+			os.Remove(e.socket)
+		}
+		listener, err := listenConfig.Listen(ctx, "unix", e.socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		return listener, nil
+	}
+
+	if e.port != 0 {
+		listener, err := listenConfig.Listen(ctx, "tcp", net.JoinHostPort(e.host, strconv.Itoa(e.port)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		return listener, nil
+	}
+
+	// Try ports until we find an available one
+	for port := DefaultPort; ; port++ {
+		listener, err := listenConfig.Listen(ctx, "tcp", net.JoinHostPort(e.host, strconv.Itoa(port)))
+		if err == nil {
+			return listener, nil
+		}
+		if !isPortInUseError(err) {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+	}
+}
+
+// Address returns the URL of the server, or unix:// and the path of its socket
 func (e *Emitter) Address() string {
+	if e.socket != "" {
+		return "unix://" + e.socket
+	}
+	if e.tls != nil {
+		return "https://" + e.addr
+	}
 	return "http://" + e.addr
 }
 
 // handleWS handles WebSocket connections
+// Clients may pass the ID of the last message they received as the "after" query parameter
+// so that only newer messages are replayed on reconnect
 func (e *Emitter) handleWS(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		if after, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "invalid after parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
 	conn, err := e.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	// Send message history to new client
-	e.historyMutex.RLock()
-	for _, msg := range e.messageHistory {
-		data, err := json.Marshal(msg)
-		if err != nil {
-			e.historyMutex.RUnlock()
-			conn.Close()
-			return
-		}
-		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-			e.historyMutex.RUnlock()
-			conn.Close()
-			return
-		}
-	}
-	e.historyMutex.RUnlock()
-
-	e.clients.Store(conn, struct{}{})
+	// Register the client before replaying so that no message is missed in between
+	// Its writer skips queued messages that were already replayed
+	c := newClient(conn, e.queueSize)
+	e.clients.Store(c, struct{}{})
 	go func() {
-		defer func() {
-			conn.Close()
-			e.clients.Delete(conn)
-		}()
-		// Wait for client disconnection
-		for {
-			if _, _, err := conn.ReadMessage(); err != nil {
-				return
-			}
-		}
+		c.readLoop()
+		e.clients.Delete(c)
 	}()
+	go c.writeLoop(e.messageHistory, after)
 }
 
 // handleVersion serves version information
@@ -201,48 +323,76 @@ func (e *Emitter) Emit(entry *entry.Entry) {
 		return
 	}
 
+	// Parse the stack so that the viewer can link its frames to the sources
+	var frames []stack.Frame
+	if entry.Structured != nil && entry.Structured.Stack != "" {
+		frames = stack.Parse(entry.Structured.Stack)
+		e.linker.Link(frames)
+	}
+
 	// Create message with timestamp and sequence number
 	e.historyMutex.Lock()
-	currentTime := time.Now().UnixMilli()
-	seq := e.sequence.Add(1) & 0xFFF // Use lower 12 bits (cycles through 0-4095)
+	// Message ID generation:
+	// 1. Shift the timestamp in milliseconds left by 12 bits to use the upper 41 bits
+	//    (supports dates until year 2286)
+	// 2. Count messages within the same millisecond in the lower 12 bits
+	// This keeps the ID within 53 bits for safe handling in JavaScript clients
+	// IDs always increase, as resuming and replaying rely on: beyond 4,096 messages
+	// in a millisecond, or when the clock goes back, the ID continues from the last one
+	e.lastID = max(e.lastID+1, time.Now().UnixMilli()<<12)
 	msg := Message{
-		// Message ID generation:
-		// 1. Shift timestamp left by 12 bits to use the upper 41 bits
-		// 2. Use sequence number in the lower 12 bits (cycles through 0-4095)
-		// This ensures the ID stays within 53 bits for safe handling in JavaScript clients
-		// and provides unique IDs for up to 4,096 messages within the same millisecond
-		ID:   (currentTime << 12) | seq,
-		Body: entry.Structured,
+		ID:     e.lastID,
+		Body:   entry.Structured,
+		Source: entry.Source,
+		Frames: frames,
 	}
 	if entry.Structured == nil {
 		msg.Body = entry.Unstructured
 	}
 
-	// Store message in history
-	e.messageHistory = append(e.messageHistory, msg)
-	e.historyMutex.Unlock()
-
 	// Marshal message to JSON
 	data, err = json.Marshal(msg)
 	if err != nil {
+		e.historyMutex.Unlock()
 		return
 	}
 
-	// Broadcast to all clients
+	// Store message in history
+	if err := e.messageHistory.Append(history.Record{ID: msg.ID, Data: data, Raw: entry.Raw}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to store message history: %v\n", err)
+	}
+	e.historyMutex.Unlock()
+
+	// Queue for all clients; slow clients never block the pipeline
+	message := queuedMessage{id: msg.ID, data: data, entry: entry}
 	e.clients.Range(func(key, _ interface{}) bool {
-		conn := key.(*websocket.Conn)
-		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-			conn.Close()
-			e.clients.Delete(conn)
-		}
+		key.(*client).enqueue(message, e.slowClient)
 		return true
 	})
 }
 
-// Close shuts down the WebSocket server
-func (e *Emitter) Close() error {
+// Close shuts down the WebSocket server, disconnects clients and closes the message history
+// Messages queued for clients are delivered first, until ctx is done
+func (e *Emitter) Close(ctx context.Context) error {
 	if e.server != nil {
-		return e.server.Close()
+		// Shutdown does not track hijacked WebSocket connections, so close them explicitly
+		if err := e.server.Shutdown(ctx); err != nil {
+			return err
+		}
+		e.clients.Range(func(key, _ interface{}) bool {
+			key.(*client).drain()
+			return true
+		})
+		e.clients.Range(func(key, _ interface{}) bool {
+			c := key.(*client)
+			select {
+			case <-c.stopped:
+			case <-ctx.Done():
+			}
+			c.close()
+			e.clients.Delete(key)
+			return true
+		})
 	}
-	return nil
+	return e.messageHistory.Close()
 }
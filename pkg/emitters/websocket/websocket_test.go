@@ -1,8 +1,19 @@
 package websocket_test
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,8 +23,43 @@ import (
 
 	wsemitter "github.com/appthrust/kutelog/pkg/emitters/websocket"
 	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/history"
+	"github.com/appthrust/kutelog/pkg/stack"
 )
 
+// authorizedGet requests a path of the emitter with its access token
+func authorizedGet(e *wsemitter.Emitter, path string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, e.Address()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+e.Token())
+	return http.DefaultClient.Do(req)
+}
+
+// wsURLOf returns the WebSocket URL of the emitter including its access token
+func wsURLOf(e *wsemitter.Emitter) string {
+	return "ws://" + strings.TrimPrefix(e.Address(), "http://") + "/ws?token=" + e.Token()
+}
+
+// failingStore is a history store whose Replay fails after replaying the given number of records
+type failingStore struct {
+	*history.Ring
+	after int
+}
+
+func (s *failingStore) Replay(after int64, fn func(history.Record) bool) error {
+	replayed := 0
+	s.Ring.Replay(after, func(record history.Record) bool {
+		if replayed == s.after {
+			return false
+		}
+		replayed++
+		return fn(record)
+	})
+	return errors.New("disk failure")
+}
+
 var _ = Describe("WebSocket Emitter", func() {
 	var (
 		emitter *wsemitter.Emitter
@@ -22,11 +68,11 @@ var _ = Describe("WebSocket Emitter", func() {
 
 	BeforeEach(func() {
 		emitter = wsemitter.NewEmitter()
-		Expect(emitter.Init()).To(Succeed())
+		Expect(emitter.Init(context.Background())).To(Succeed())
 
 		// Get WebSocket URL
 		addr := emitter.Address()
-		wsURL = "ws://" + strings.TrimPrefix(addr, "http://") + "/ws"
+		wsURL = "ws://" + strings.TrimPrefix(addr, "http://") + "/ws?token=" + emitter.Token()
 	})
 
 	Context("when initializing", func() {
@@ -35,7 +81,7 @@ var _ = Describe("WebSocket Emitter", func() {
 		})
 
 		It("serves viewer page", func() {
-			resp, err := http.Get(emitter.Address())
+			resp, err := authorizedGet(emitter, "/")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(resp.StatusCode).To(Equal(http.StatusOK))
 			Expect(resp.Header.Get("Content-Type")).To(Equal("text/html"))
@@ -100,6 +146,38 @@ var _ = Describe("WebSocket Emitter", func() {
 			Expect(received).To(Equal("plain text log"))
 		})
 
+		It("broadcasts source of the entry", func() {
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			emitter.Emit(&entry.Entry{
+				Unstructured: "from a pod",
+				Source: &entry.Source{
+					Namespace: "default",
+					Pod:       "app-1",
+					Container: "manager",
+				},
+			})
+			emitter.Emit(&entry.Entry{
+				Unstructured: "from stdin",
+			})
+
+			_, message, err := ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			var msg wsemitter.Message
+			Expect(json.Unmarshal(message, &msg)).To(Succeed())
+			Expect(msg.Source).To(Equal(&entry.Source{
+				Namespace: "default",
+				Pod:       "app-1",
+				Container: "manager",
+			}))
+
+			_, message, err = ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(message)).NotTo(ContainSubstring(`"source"`))
+		})
+
 		It("handles multiple clients", func() {
 			ws1, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
 			Expect(err).NotTo(HaveOccurred())
@@ -167,11 +245,780 @@ var _ = Describe("WebSocket Emitter", func() {
 				Expect(received).To(Equal(expected))
 			}
 		})
+
+		It("replays only messages after the given ID", func() {
+			emitter.Emit(&entry.Entry{Unstructured: "message 1"})
+			emitter.Emit(&entry.Entry{Unstructured: "message 2"})
+
+			// Find the ID of the first message
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, message, err := ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			ws.Close()
+			var first wsemitter.Message
+			Expect(json.Unmarshal(message, &first)).To(Succeed())
+
+			// Reconnect resuming after the first message
+			ws, _, err = websocket.DefaultDialer.Dial(fmt.Sprintf("%s&after=%d", wsURL, first.ID), nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			_, message, err = ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			var msg wsemitter.Message
+			Expect(json.Unmarshal(message, &msg)).To(Succeed())
+			Expect(msg.ID).To(BeNumerically(">", first.ID))
+			Expect(msg.Body).To(Equal("message 2"))
+		})
+
+		It("disconnects clients on close", func() {
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			// Make sure the connection is registered before closing
+			emitter.Emit(&entry.Entry{Unstructured: "before close"})
+			_, _, err = ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(emitter.Close(context.Background())).To(Succeed())
+			_, _, err = ws.ReadMessage()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("delivers queued messages before disconnecting clients on close", func() {
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			emitter.Emit(&entry.Entry{Unstructured: "before close"})
+			_, _, err = ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+
+			for i := 0; i < 500; i++ {
+				emitter.Emit(&entry.Entry{Unstructured: strconv.Itoa(i)})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			Expect(emitter.Close(ctx)).To(Succeed())
+
+			for i := 0; i < 500; i++ {
+				_, message, err := ws.ReadMessage()
+				Expect(err).NotTo(HaveOccurred())
+				var msg wsemitter.Message
+				Expect(json.Unmarshal(message, &msg)).To(Succeed())
+				Expect(msg.Body).To(Equal(strconv.Itoa(i)))
+			}
+			_, _, err = ws.ReadMessage()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects an invalid resume ID", func() {
+			_, resp, err := websocket.DefaultDialer.Dial(wsURL+"&after=abc", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("keeps IDs increasing beyond 4096 messages per millisecond", func() {
+			store := history.NewRing(&history.RingOptions{})
+			unbounded := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{History: store})
+			for i := 0; i < 3*4096; i++ {
+				unbounded.Emit(&entry.Entry{Unstructured: "burst"})
+			}
+
+			var ids []int64
+			Expect(store.Replay(0, func(r history.Record) bool {
+				ids = append(ids, r.ID)
+				return true
+			})).To(Succeed())
+			Expect(ids).To(HaveLen(3 * 4096))
+			for i := 1; i < len(ids); i++ {
+				Expect(ids[i]).To(BeNumerically(">", ids[i-1]))
+			}
+		})
+
+		It("replays only what the history store keeps", func() {
+			bounded := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{
+				History: history.NewRing(&history.RingOptions{MaxEntries: 1}),
+			})
+			Expect(bounded.Init(context.Background())).To(Succeed())
+			defer bounded.Close(context.Background())
+
+			bounded.Emit(&entry.Entry{Unstructured: "evicted"})
+			bounded.Emit(&entry.Entry{Unstructured: "kept"})
+
+			ws, _, err := websocket.DefaultDialer.Dial(wsURLOf(bounded), nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			_, message, err := ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			var msg wsemitter.Message
+			Expect(json.Unmarshal(message, &msg)).To(Succeed())
+			Expect(msg.Body).To(Equal("kept"))
+		})
+	})
+
+	Context("with stack traces", func() {
+		It("sends the frames of the stack with editor links", func() {
+			linker, err := stack.NewLinker(&stack.LinkerOptions{
+				Editor:  "vscode",
+				PathMap: []stack.PathMapping{{From: "/workspace", To: "/home/me/project"}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			e := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{Linker: linker})
+			Expect(e.Init(context.Background())).To(Succeed())
+			DeferCleanup(e.Close, context.Background())
+
+			ws, _, err := websocket.DefaultDialer.Dial(wsURLOf(e), nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			e.Emit(&entry.Entry{Structured: &entry.Structured{
+				Level:   entry.LevelError,
+				Message: "reconcile failed",
+				Stack:   "main.reconcile\n\t/workspace/main.go:42\nmain.main\n\t/workspace/main.go:8",
+			}})
+			e.Emit(&entry.Entry{Structured: &entry.Structured{Level: entry.LevelInfo, Message: "no stack"}})
+
+			var msg wsemitter.Message
+			Expect(ws.ReadJSON(&msg)).To(Succeed())
+			Expect(msg.Frames).To(Equal([]stack.Frame{
+				{Function: "main.reconcile", File: "/workspace/main.go", Line: 42, URL: "vscode://file/home/me/project/main.go:42"},
+				{Function: "main.main", File: "/workspace/main.go", Line: 8, URL: "vscode://file/home/me/project/main.go:8"},
+			}))
+			// the stack is still sent as it was logged
+			Expect(msg.Body.(map[string]interface{})["stack"]).To(HavePrefix("main.reconcile\n"))
+
+			msg = wsemitter.Message{}
+			Expect(ws.ReadJSON(&msg)).To(Succeed())
+			Expect(msg.Frames).To(BeEmpty())
+		})
+
+		It("sends frames without links by default", func() {
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			emitter.Emit(&entry.Entry{Structured: &entry.Structured{
+				Level:   entry.LevelError,
+				Message: "reconcile failed",
+				Stack:   "main.main\n\t/app/main.go:8",
+			}})
+
+			var msg wsemitter.Message
+			Expect(ws.ReadJSON(&msg)).To(Succeed())
+			Expect(msg.Frames).To(Equal([]stack.Frame{{Function: "main.main", File: "/app/main.go", Line: 8}}))
+		})
+	})
+
+	Context("with slow clients", func() {
+		// Large messages fill the socket buffers of a client that does not read
+		payload := strings.Repeat("x", 256*1024)
+
+		// connectStalled starts an emitter with the given policy and connects a client that does not read yet
+		connectStalled := func(policy wsemitter.SlowClientPolicy) (*wsemitter.Emitter, *websocket.Conn) {
+			slow := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{
+				QueueSize:  1,
+				SlowClient: policy,
+			})
+			Expect(slow.Init(context.Background())).To(Succeed())
+			DeferCleanup(slow.Close, context.Background())
+
+			ws, _, err := websocket.DefaultDialer.Dial(wsURLOf(slow), nil)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(ws.Close)
+			return slow, ws
+		}
+
+		// emitMany emits messages in the background and returns a channel closed when done
+		emitMany := func(slow *wsemitter.Emitter, n int) chan struct{} {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < n; i++ {
+					slow.Emit(&entry.Entry{Unstructured: payload})
+				}
+			}()
+			return done
+		}
+
+		It("does not block emitting and notifies the client of dropped messages", func() {
+			slow, ws := connectStalled(wsemitter.SlowClientDrop)
+			Eventually(emitMany(slow, 100), 5*time.Second).Should(BeClosed())
+
+			// The client catches up and learns how many messages it missed
+			received, dropped := 0, 0
+			for received+dropped < 100 {
+				ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+				_, message, err := ws.ReadMessage()
+				Expect(err).NotTo(HaveOccurred())
+				var msg wsemitter.Message
+				Expect(json.Unmarshal(message, &msg)).To(Succeed())
+				if msg.Type == wsemitter.MessageTypeDropped {
+					dropped += int(msg.Dropped)
+				} else {
+					received++
+				}
+			}
+			Expect(dropped).To(BeNumerically(">", 0))
+		})
+
+		It("disconnects slow clients with the disconnect policy", func() {
+			slow, ws := connectStalled(wsemitter.SlowClientDisconnect)
+			Eventually(emitMany(slow, 100), 5*time.Second).Should(BeClosed())
+
+			// Buffered messages may still arrive, but the connection ends
+			var err error
+			for err == nil {
+				ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+				_, _, err = ws.ReadMessage()
+			}
+			var netErr interface{ Timeout() bool }
+			if errors.As(err, &netErr) {
+				Expect(netErr.Timeout()).To(BeFalse(), "expected the server to close the connection")
+			}
+		})
+	})
+
+	Context("with subscriptions", func() {
+		var ws *websocket.Conn
+
+		BeforeEach(func() {
+			var err error
+			ws, _, err = websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(ws.Close)
+		})
+
+		read := func() wsemitter.Message {
+			ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, message, err := ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			var msg wsemitter.Message
+			Expect(json.Unmarshal(message, &msg)).To(Succeed())
+			return msg
+		}
+
+		subscribe := func(filter wsemitter.Filter) {
+			Expect(ws.WriteJSON(wsemitter.Subscription{
+				Type:   wsemitter.MessageTypeSubscribe,
+				Filter: filter,
+			})).To(Succeed())
+			Expect(read().Type).To(Equal(wsemitter.MessageTypeSubscribed))
+		}
+
+		structured := func(level entry.Level, message string, data map[string]interface{}) *entry.Entry {
+			return &entry.Entry{Structured: &entry.Structured{
+				Timestamp: time.Now(),
+				Level:     level,
+				Message:   message,
+				Data:      data,
+			}}
+		}
+
+		messageOf := func(msg wsemitter.Message) string {
+			if body, ok := msg.Body.(string); ok {
+				return body
+			}
+			return msg.Body.(map[string]interface{})["message"].(string)
+		}
+
+		It("delivers only messages at or above the minimum level", func() {
+			subscribe(wsemitter.Filter{Level: entry.LevelWarning})
+
+			emitter.Emit(structured(entry.LevelDebug, "debug", nil))
+			emitter.Emit(&entry.Entry{Unstructured: "unstructured"})
+			emitter.Emit(structured(entry.LevelError, "error", nil))
+
+			Expect(messageOf(read())).To(Equal("error"))
+		})
+
+		It("delivers only messages matching the message pattern", func() {
+			subscribe(wsemitter.Filter{Message: "^reconcil(e|ing)"})
+
+			emitter.Emit(structured(entry.LevelInfo, "starting manager", nil))
+			emitter.Emit(&entry.Entry{Unstructured: "reconcile started"})
+			emitter.Emit(structured(entry.LevelInfo, "reconciling", nil))
+
+			Expect(messageOf(read())).To(Equal("reconcile started"))
+			Expect(messageOf(read())).To(Equal("reconciling"))
+		})
+
+		It("delivers only messages with matching data", func() {
+			subscribe(wsemitter.Filter{Data: map[string]string{
+				"controller":       "pod",
+				"request.attempts": "2",
+			}})
+
+			emitter.Emit(structured(entry.LevelInfo, "other controller", map[string]interface{}{
+				"controller": "node",
+				"request":    map[string]interface{}{"attempts": 2},
+			}))
+			emitter.Emit(structured(entry.LevelInfo, "first attempt", map[string]interface{}{
+				"controller": "pod",
+				"request":    map[string]interface{}{"attempts": 1},
+			}))
+			emitter.Emit(structured(entry.LevelInfo, "retried", map[string]interface{}{
+				"controller": "pod",
+				"request":    map[string]interface{}{"attempts": 2},
+			}))
+
+			Expect(messageOf(read())).To(Equal("retried"))
+		})
+
+		It("matches numbers by value", func() {
+			subscribe(wsemitter.Filter{Data: map[string]string{"count": "1000000", "ready": "true"}})
+
+			emitter.Emit(structured(entry.LevelInfo, "fewer", map[string]interface{}{"count": 999999.0, "ready": true}))
+			emitter.Emit(structured(entry.LevelInfo, "not ready", map[string]interface{}{"count": 1e6, "ready": false}))
+			emitter.Emit(structured(entry.LevelInfo, "float", map[string]interface{}{"count": 1e6, "ready": true}))
+			emitter.Emit(structured(entry.LevelInfo, "int", map[string]interface{}{"count": 1000000, "ready": true}))
+
+			Expect(messageOf(read())).To(Equal("float"))
+			Expect(messageOf(read())).To(Equal("int"))
+		})
+
+		It("matches data keys containing dots like queries do", func() {
+			subscribe(wsemitter.Filter{Data: map[string]string{
+				"labels.app.kubernetes.io/name": "web",
+			}})
+
+			emitter.Emit(structured(entry.LevelInfo, "other app", map[string]interface{}{
+				"labels": map[string]interface{}{"app.kubernetes.io/name": "db"},
+			}))
+			emitter.Emit(structured(entry.LevelInfo, "web", map[string]interface{}{
+				"labels": map[string]interface{}{"app.kubernetes.io/name": "web"},
+			}))
+
+			Expect(messageOf(read())).To(Equal("web"))
+		})
+
+		It("delivers only messages from the matching source", func() {
+			subscribe(wsemitter.Filter{Source: &entry.Source{Pod: "api-0"}})
+
+			emitter.Emit(&entry.Entry{Unstructured: "no source"})
+			emitter.Emit(&entry.Entry{Unstructured: "other pod", Source: &entry.Source{Pod: "api-1"}})
+			emitter.Emit(&entry.Entry{Unstructured: "matching pod", Source: &entry.Source{Namespace: "default", Pod: "api-0"}})
+
+			Expect(messageOf(read())).To(Equal("matching pod"))
+		})
+
+		It("delivers only messages matching the query", func() {
+			subscribe(wsemitter.Filter{Query: `level >= warning && data.controller == "pod"`})
+
+			emitter.Emit(structured(entry.LevelError, "other controller", map[string]interface{}{"controller": "node"}))
+			emitter.Emit(structured(entry.LevelInfo, "info", map[string]interface{}{"controller": "pod"}))
+			emitter.Emit(structured(entry.LevelError, "error", map[string]interface{}{"controller": "pod"}))
+
+			Expect(messageOf(read())).To(Equal("error"))
+		})
+
+		It("replays only matching messages from history", func() {
+			emitter.Emit(structured(entry.LevelInfo, "info", nil))
+			emitter.Emit(structured(entry.LevelError, "error", nil))
+			Expect(messageOf(read())).To(Equal("info"))
+			Expect(messageOf(read())).To(Equal("error"))
+
+			subscribe(wsemitter.Filter{Level: entry.LevelError})
+			Expect(messageOf(read())).To(Equal("error"))
+
+			// Clearing the filter replays everything again
+			subscribe(wsemitter.Filter{})
+			Expect(messageOf(read())).To(Equal("info"))
+			Expect(messageOf(read())).To(Equal("error"))
+		})
+
+		It("reports an invalid subscription and keeps the previous one", func() {
+			subscribe(wsemitter.Filter{Level: entry.LevelError})
+
+			Expect(ws.WriteJSON(wsemitter.Subscription{
+				Type:   wsemitter.MessageTypeSubscribe,
+				Filter: wsemitter.Filter{Message: "("},
+			})).To(Succeed())
+			msg := read()
+			Expect(msg.Type).To(Equal(wsemitter.MessageTypeError))
+			Expect(msg.Error).To(ContainSubstring("invalid message pattern"))
+
+			Expect(ws.WriteJSON(wsemitter.Subscription{
+				Type:   wsemitter.MessageTypeSubscribe,
+				Filter: wsemitter.Filter{Query: "level >= verbose"},
+			})).To(Succeed())
+			msg = read()
+			Expect(msg.Type).To(Equal(wsemitter.MessageTypeError))
+			Expect(msg.Error).To(ContainSubstring("invalid query: column 10"))
+
+			emitter.Emit(structured(entry.LevelInfo, "info", nil))
+			emitter.Emit(structured(entry.LevelError, "error", nil))
+			Expect(messageOf(read())).To(Equal("error"))
+		})
+	})
+
+	Context("when exporting history", func() {
+		get := func(params string) (*http.Response, string) {
+			resp, err := authorizedGet(emitter, "/export"+params)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			return resp, string(body)
+		}
+
+		old := time.Date(2025, 1, 30, 15, 0, 0, 0, time.UTC)
+		recent := time.Date(2025, 1, 30, 16, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			emitter.Emit(&entry.Entry{
+				Structured: &entry.Structured{
+					Timestamp: old,
+					Level:     entry.LevelInfo,
+					Message:   "starting",
+					Data:      map[string]interface{}{"controller": "machine"},
+				},
+				Raw: `{"level":"info","msg":"starting","controller":"machine"}`,
+			})
+			emitter.Emit(&entry.Entry{Unstructured: "plain text log", Source: &entry.Source{Pod: "web-0"}})
+			emitter.Emit(&entry.Entry{
+				Structured: &entry.Structured{
+					Timestamp: recent,
+					Level:     entry.LevelError,
+					Message:   "failed",
+					Data: map[string]interface{}{
+						"request": map[string]interface{}{"attempts": 2.0},
+						"message": "connection refused",
+					},
+				},
+			})
+		})
+
+		It("exports JSON lines replayable by kutelog replay", func() {
+			resp, body := get("")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+			Expect(resp.Header.Get("Content-Disposition")).To(MatchRegexp(`^attachment; filename="kutelog-\d{8}-\d{6}\.jsonl"$`))
+
+			lines := strings.Split(strings.TrimSpace(body), "\n")
+			Expect(lines).To(HaveLen(3))
+			var e entry.Entry
+			Expect(json.Unmarshal([]byte(lines[1]), &e)).To(Succeed())
+			Expect(e).To(Equal(entry.Entry{Unstructured: "plain text log", Source: &entry.Source{Pod: "web-0"}}))
+		})
+
+		It("keeps the original lines out of messages sent to clients", func() {
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+
+			_, message, err := ws.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(message)).To(ContainSubstring(`"message":"starting"`))
+			Expect(string(message)).NotTo(ContainSubstring(`"raw"`))
+		})
+
+		It("exports CSV with a column per data key", func() {
+			resp, body := get("?format=csv")
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/csv; charset=utf-8"))
+			Expect(body).To(Equal(strings.Join([]string{
+				"timestamp,level,message,source,data.controller,data.message,data.request.attempts,stack",
+				"2025-01-30T15:00:00Z,info,starting,,machine,,,",
+				",,plain text log,web-0,,,,",
+				"2025-01-30T16:00:00Z,error,failed,,,connection refused,2,",
+				"",
+			}, "\n")))
+		})
+
+		It("exports the original lines", func() {
+			_, body := get("?format=raw")
+			Expect(body).To(Equal(`{"level":"info","msg":"starting","controller":"machine"}` + "\nplain text log\nfailed\n"))
+		})
+
+		It("filters by level, time range and query", func() {
+			_, body := get("?format=raw&level=warning")
+			Expect(body).To(Equal("failed\n"))
+
+			_, body = get("?format=raw&since=2025-01-30T15:30:00Z&until=2025-01-30T17:00:00Z")
+			Expect(body).To(Equal("failed\n"))
+
+			// plain lines are dated by when they were received
+			_, body = get("?format=raw&since=1m")
+			Expect(body).To(Equal("plain text log\n"))
+
+			_, body = get("?format=raw&query=" + url.QueryEscape(`data.controller == "machine"`))
+			Expect(body).To(HavePrefix(`{"level":"info"`))
+		})
+
+		It("writes entries as they are replayed", func() {
+			for _, after := range []int{0, 1} {
+				failing := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{
+					History: &failingStore{Ring: history.NewRing(nil), after: after},
+				})
+				Expect(failing.Init(context.Background())).To(Succeed())
+				defer failing.Close(context.Background())
+				failing.Emit(&entry.Entry{Unstructured: "first"})
+				failing.Emit(&entry.Entry{Unstructured: "second"})
+
+				resp, err := authorizedGet(failing, "/export?format=raw")
+				Expect(err).NotTo(HaveOccurred())
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				Expect(err).NotTo(HaveOccurred())
+				if after == 0 {
+					Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+					Expect(string(body)).To(ContainSubstring("disk failure"))
+				} else {
+					// the status is sent with the first entry
+					Expect(resp.StatusCode).To(Equal(http.StatusOK))
+					Expect(string(body)).To(Equal("first\n"))
+				}
+			}
+		})
+
+		It("rejects invalid parameters", func() {
+			for _, params := range []string{"?format=xml", "?since=yesterday", "?level=verbose", "?query=level%20%3E%3D"} {
+				resp, _ := get(params)
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest), params)
+			}
+		})
+	})
+
+	Context("with listen options", func() {
+		start := func(options *wsemitter.EmitterOptions) (*wsemitter.Emitter, error) {
+			e := wsemitter.NewEmitterWithOptions(options)
+			if err := e.Init(context.Background()); err != nil {
+				return nil, err
+			}
+			DeferCleanup(e.Close, context.Background())
+			return e, nil
+		}
+
+		It("tries the next port when the default one is in use", func() {
+			// the emitter of BeforeEach holds the first free port
+			next, err := start(&wsemitter.EmitterOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(next.Address()).NotTo(Equal(emitter.Address()))
+		})
+
+		It("fails instead of trying other ports when the port is fixed", func() {
+			_, port, err := net.SplitHostPort(strings.TrimPrefix(emitter.Address(), "http://"))
+			Expect(err).NotTo(HaveOccurred())
+			fixed, err := strconv.Atoi(port)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = start(&wsemitter.EmitterOptions{Port: fixed})
+			Expect(err).To(MatchError(ContainSubstring("failed to listen")))
+		})
+
+		It("listens on the given host", func() {
+			if l, err := net.Listen("tcp", "[::1]:0"); err != nil {
+				Skip("IPv6 is not available")
+			} else {
+				l.Close()
+			}
+			e, err := start(&wsemitter.EmitterOptions{Host: "::1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.Address()).To(HavePrefix("http://[::1]:"))
+
+			resp, err := authorizedGet(e, "/version")
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("listens on a Unix socket", func() {
+			socket := filepath.Join(GinkgoT().TempDir(), "kutelog.sock")
+			e, err := start(&wsemitter.EmitterOptions{Socket: socket})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.Address()).To(Equal("unix://" + socket))
+
+			client := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			}}
+			resp, err := client.Get("http://kutelog/version?token=" + e.Token())
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			dialer := websocket.Dialer{NetDial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", socket)
+			}}
+			ws, _, err := dialer.Dial("ws://kutelog/ws?token="+e.Token(), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ws.Close()
+		})
+
+		It("replaces a socket file left behind by a killed run", func() {
+			socket := filepath.Join(GinkgoT().TempDir(), "kutelog.sock")
+			stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+			Expect(err).NotTo(HaveOccurred())
+			stale.SetUnlinkOnClose(false)
+			stale.Close()
+
+			_, err = start(&wsemitter.EmitterOptions{Socket: socket})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("fails if the socket is served by another process", func() {
+			socket := filepath.Join(GinkgoT().TempDir(), "kutelog.sock")
+			_, err := start(&wsemitter.EmitterOptions{Socket: socket})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = start(&wsemitter.EmitterOptions{Socket: socket})
+			Expect(err).To(MatchError(ContainSubstring("already in use")))
+			conn, err := net.Dial("unix", socket)
+			Expect(err).NotTo(HaveOccurred())
+			conn.Close()
+		})
+
+		It("serves TLS with a self-signed certificate generated on first run", func() {
+			dir := GinkgoT().TempDir()
+			e, err := start(&wsemitter.EmitterOptions{TLS: &wsemitter.TLSOptions{Dir: dir}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(e.Address()).To(HavePrefix("https://127.0.0.1:"))
+
+			certPEM, err := os.ReadFile(filepath.Join(dir, "cert.pem"))
+			Expect(err).NotTo(HaveOccurred())
+			roots := x509.NewCertPool()
+			Expect(roots.AppendCertsFromPEM(certPEM)).To(BeTrue())
+			tlsConfig := &tls.Config{RootCAs: roots}
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			resp, err := client.Get(e.Address() + "/version?token=" + e.Token())
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			dialer := websocket.Dialer{TLSClientConfig: tlsConfig}
+			ws, _, err := dialer.Dial("wss://"+strings.TrimPrefix(e.Address(), "https://")+"/ws?token="+e.Token(), nil)
+			Expect(err).NotTo(HaveOccurred())
+			ws.Close()
+
+			// the certificate is reused by the next run
+			_, err = start(&wsemitter.EmitterOptions{TLS: &wsemitter.TLSOptions{Dir: dir}})
+			Expect(err).NotTo(HaveOccurred())
+			reused, err := os.ReadFile(filepath.Join(dir, "cert.pem"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reused).To(Equal(certPEM))
+		})
+
+		It("fails if the configured certificate cannot be loaded", func() {
+			_, err := start(&wsemitter.EmitterOptions{TLS: &wsemitter.TLSOptions{
+				CertFile: "missing.pem",
+				KeyFile:  "missing-key.pem",
+			}})
+			Expect(err).To(MatchError(ContainSubstring("failed to load TLS certificate")))
+		})
+	})
+
+	Context("with access control", func() {
+		noRedirect := &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+		It("rejects requests without a valid token", func() {
+			for _, path := range []string{"/", "/version", "/export", "/ws"} {
+				resp, err := http.Get(emitter.Address() + path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized), path)
+
+				resp, err = http.Get(emitter.Address() + path + "?token=wrong")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized), path)
+			}
+
+			_, resp, err := websocket.DefaultDialer.Dial(
+				"ws://"+strings.TrimPrefix(emitter.Address(), "http://")+"/ws", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("prints the token in the viewer URL", func() {
+			Expect(emitter.Token()).To(HaveLen(32))
+			Expect(emitter.URL()).To(Equal(emitter.Address() + "/?token=" + emitter.Token()))
+		})
+
+		It("moves the token of the viewer URL into a cookie", func() {
+			resp, err := noRedirect.Get(emitter.URL())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusSeeOther))
+			Expect(resp.Header.Get("Location")).To(Equal("/"))
+			cookies := resp.Cookies()
+			Expect(cookies).To(HaveLen(1))
+			Expect(cookies[0].Value).To(Equal(emitter.Token()))
+			Expect(cookies[0].HttpOnly).To(BeTrue())
+			Expect(cookies[0].SameSite).To(Equal(http.SameSiteStrictMode))
+
+			req, err := http.NewRequest(http.MethodGet, emitter.Address()+"/version", nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.AddCookie(cookies[0])
+			resp, err = http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("rejects WebSocket connections from other origins", func() {
+			header := http.Header{"Origin": {"http://evil.example"}}
+			_, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			// the viewer itself is always allowed
+			header = http.Header{"Origin": {emitter.Address()}}
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+			Expect(err).NotTo(HaveOccurred())
+			ws.Close()
+		})
+
+		It("accepts allowed origins", func() {
+			e := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{
+				AllowedOrigins: []string{"http://localhost:3000"},
+			})
+			Expect(e.Init(context.Background())).To(Succeed())
+			DeferCleanup(e.Close, context.Background())
+
+			header := http.Header{"Origin": {"http://localhost:3000"}}
+			ws, _, err := websocket.DefaultDialer.Dial(wsURLOf(e), header)
+			Expect(err).NotTo(HaveOccurred())
+			ws.Close()
+
+			header = http.Header{"Origin": {"http://localhost:3001"}}
+			_, resp, err := websocket.DefaultDialer.Dial(wsURLOf(e), header)
+			Expect(err).To(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("uses the configured token", func() {
+			e := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{Token: "secret"})
+			Expect(e.Init(context.Background())).To(Succeed())
+			DeferCleanup(e.Close, context.Background())
+
+			Expect(e.Token()).To(Equal("secret"))
+			resp, err := http.Get(e.Address() + "/version?token=secret")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("serves without a token if authentication is disabled", func() {
+			e := wsemitter.NewEmitterWithOptions(&wsemitter.EmitterOptions{DisableAuth: true})
+			Expect(e.Init(context.Background())).To(Succeed())
+			DeferCleanup(e.Close, context.Background())
+
+			Expect(e.Token()).To(BeEmpty())
+			Expect(e.URL()).To(Equal(e.Address() + "/"))
+			resp, err := http.Get(e.Address() + "/version")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
 	})
 
 	Context("when serving HTTP endpoints", func() {
 		It("serves version information", func() {
-			resp, err := http.Get(emitter.Address() + "/version")
+			resp, err := authorizedGet(emitter, "/version")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(resp.StatusCode).To(Equal(http.StatusOK))
 			Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
@@ -183,13 +1030,13 @@ var _ = Describe("WebSocket Emitter", func() {
 		})
 
 		It("returns 404 for non-existent files", func() {
-			resp, err := http.Get(emitter.Address() + "/nonexistent")
+			resp, err := authorizedGet(emitter, "/nonexistent")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
 		})
 
 		It("serves index page with correct Content-Type", func() {
-			resp, err := http.Get(emitter.Address() + "/")
+			resp, err := authorizedGet(emitter, "/")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(resp.StatusCode).To(Equal(http.StatusOK))
 			Expect(resp.Header.Get("Content-Type")).To(Equal("text/html"))
@@ -1,25 +1,148 @@
 package entry
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
 
+// Entry is a log entry, either Structured if its format was recognized or the Unstructured line
+// The JSON encoding is used by files written by kutelog, so that they can be replayed
 type Entry struct {
-	Structured   *Structured
-	Unstructured string
+	Structured   *Structured `json:"structured,omitempty"`
+	Unstructured string      `json:"unstructured,omitempty"`
+	Source       *Source     `json:"source,omitempty"` // where the entry came from; nil for the default input
+	// Raw holds the original lines of a Structured entry, for exporting the input as it was written
+	// Empty for unstructured entries, whose text is the line itself, and for entries not read from text
+	Raw string `json:"raw,omitempty"`
+}
+
+// Source describes the origin of an entry so that merged streams can be told apart
+type Source struct {
+	Name      string `json:"name,omitempty"`      // user-given name of the stream
+	Namespace string `json:"namespace,omitempty"` // Kubernetes namespace of the pod
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	File      string `json:"file,omitempty"`   // path of the file the entry was read from
+	Stream    string `json:"stream,omitempty"` // standard stream, i.e. stdin, stdout or stderr
+}
+
+// String formats the source as shown in the viewer, e.g. name:namespace/pod:container
+func (s *Source) String() string {
+	if s == nil {
+		return ""
+	}
+	var parts []string
+	if s.Name != "" {
+		parts = append(parts, s.Name)
+	}
+	if s.Pod != "" {
+		if s.Namespace != "" {
+			parts = append(parts, s.Namespace+"/"+s.Pod)
+		} else {
+			parts = append(parts, s.Pod)
+		}
+	}
+	for _, part := range []string{s.Container, s.File, s.Stream} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ":")
 }
 
 type Structured struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Level     Level                  `json:"level"`
+	Verbosity int                    `json:"verbosity,omitempty"` // logr V-level of debug logs, e.g. 2 for V(2)
 	Message   string                 `json:"message"`
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Stack     string                 `json:"stack,omitempty"`
+	// ReadTime is set when the line had no time, so that Timestamp is when it was read
+	ReadTime bool `json:"readTime,omitempty"`
+}
+
+// FlatData returns the data with the keys of nested objects joined by dots, e.g. request.method
+func (s *Structured) FlatData() map[string]interface{} {
+	flat := make(map[string]interface{}, len(s.Data))
+	flatten(flat, "", s.Data)
+	return flat
+}
+
+func flatten(flat map[string]interface{}, prefix string, data map[string]interface{}) {
+	for key, value := range data {
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(flat, prefix+key+".", nested)
+			continue
+		}
+		flat[prefix+key] = value
+	}
+}
+
+// FormatValue formats a data value for text output: strings as they are and anything else as compact JSON
+func FormatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
 }
 
 type Level string
 
 const (
+	LevelTrace   Level = "trace"
+	LevelDebug   Level = "debug"
 	LevelInfo    Level = "info"
 	LevelWarning Level = "warning"
 	LevelError   Level = "error"
-	LevelDebug   Level = "debug"
+	LevelPanic   Level = "panic"
+	LevelFatal   Level = "fatal"
 )
+
+// levelSeverities orders the known levels from least to most severe
+var levelSeverities = map[Level]int{
+	LevelTrace:   0,
+	LevelDebug:   1,
+	LevelInfo:    2,
+	LevelWarning: 3,
+	LevelError:   4,
+	LevelPanic:   5,
+	LevelFatal:   6,
+}
+
+// Severity returns the rank of the level, higher being more severe
+// Levels not known to kutelog rank as info
+func (l Level) Severity() int {
+	if severity, ok := levelSeverities[l]; ok {
+		return severity
+	}
+	return levelSeverities[LevelInfo]
+}
+
+// ParseLevel converts a level name as written by common logging libraries to Level
+// Names are matched case-insensitively
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "trace", "trce":
+		return LevelTrace, nil
+	case "debug", "dbug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarning, nil
+	case "error", "eror", "err":
+		return LevelError, nil
+	case "dpanic", "panic":
+		return LevelPanic, nil
+	case "fatal", "crit", "critical":
+		return LevelFatal, nil
+	default:
+		return "", fmt.Errorf("unknown level: %s", name)
+	}
+}
@@ -0,0 +1,30 @@
+package history
+
+import "fmt"
+
+// Record is a single encoded message kept in a history store
+type Record struct {
+	ID   int64  // monotonically increasing message ID
+	Data []byte // encoded message as sent to clients
+	Raw  string // original lines of the entry, kept for exporting but not sent to clients
+}
+
+// Store keeps recently emitted messages so they can be replayed to clients that connect later
+type Store interface {
+	// Append adds a record to the end of the history
+	// IDs must strictly increase, which Replay relies on; records with a smaller or equal ID are rejected
+	Append(record Record) error
+	// Replay calls fn for every record with an ID greater than after, in order
+	// Replay stops early when fn returns false
+	Replay(after int64, fn func(Record) bool) error
+	// Close releases resources held by the store
+	Close() error
+}
+
+// checkID rejects a record ID that does not follow the last one
+func checkID(id, lastID int64) error {
+	if id <= lastID {
+		return fmt.Errorf("record ID %d is not greater than the last ID %d", id, lastID)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package history_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/history"
+)
+
+// record creates a test record whose data is a small JSON document
+func record(id int64) history.Record {
+	return history.Record{ID: id, Data: []byte(fmt.Sprintf(`{"id":%d}`, id))}
+}
+
+// replayIDs collects the IDs replayed after the given ID
+func replayIDs(store history.Store, after int64) []int64 {
+	var ids []int64
+	Expect(store.Replay(after, func(r history.Record) bool {
+		ids = append(ids, r.ID)
+		return true
+	})).To(Succeed())
+	return ids
+}
+
+var _ = Describe("History", func() {
+	Describe("Ring", func() {
+		It("keeps all records when unbounded", func() {
+			ring := history.NewRing(nil)
+			for i := int64(1); i <= 100; i++ {
+				Expect(ring.Append(record(i))).To(Succeed())
+			}
+			Expect(ring.Len()).To(Equal(100))
+			Expect(replayIDs(ring, 0)).To(HaveLen(100))
+		})
+
+		It("drops the oldest records beyond MaxEntries", func() {
+			ring := history.NewRing(&history.RingOptions{MaxEntries: 3})
+			for i := int64(1); i <= 5; i++ {
+				Expect(ring.Append(record(i))).To(Succeed())
+			}
+			Expect(replayIDs(ring, 0)).To(Equal([]int64{3, 4, 5}))
+		})
+
+		It("drops the oldest records beyond MaxBytes", func() {
+			// each record is 8 bytes: {"id":N}
+			ring := history.NewRing(&history.RingOptions{MaxBytes: 20})
+			for i := int64(1); i <= 5; i++ {
+				Expect(ring.Append(record(i))).To(Succeed())
+			}
+			Expect(replayIDs(ring, 0)).To(Equal([]int64{4, 5}))
+			Expect(ring.Bytes()).To(Equal(int64(16)))
+		})
+
+		It("counts the raw lines against MaxBytes", func() {
+			ring := history.NewRing(&history.RingOptions{MaxBytes: 20})
+			for i := int64(1); i <= 2; i++ {
+				r := record(i)
+				r.Raw = "raw line"
+				Expect(ring.Append(r)).To(Succeed())
+			}
+			Expect(replayIDs(ring, 0)).To(Equal([]int64{2}))
+			Expect(ring.Bytes()).To(Equal(int64(16)))
+		})
+
+		It("keeps the newest record even if it exceeds MaxBytes", func() {
+			ring := history.NewRing(&history.RingOptions{MaxBytes: 1})
+			Expect(ring.Append(record(1))).To(Succeed())
+			Expect(ring.Append(record(2))).To(Succeed())
+			Expect(replayIDs(ring, 0)).To(Equal([]int64{2}))
+		})
+
+		It("replays only records newer than the given ID", func() {
+			ring := history.NewRing(&history.RingOptions{MaxEntries: 4})
+			for i := int64(1); i <= 6; i++ {
+				Expect(ring.Append(record(i))).To(Succeed())
+			}
+			Expect(replayIDs(ring, 4)).To(Equal([]int64{5, 6}))
+			Expect(replayIDs(ring, 6)).To(BeEmpty())
+		})
+
+		It("stops replaying when the callback returns false", func() {
+			ring := history.NewRing(nil)
+			for i := int64(1); i <= 3; i++ {
+				Expect(ring.Append(record(i))).To(Succeed())
+			}
+			var ids []int64
+			Expect(ring.Replay(0, func(r history.Record) bool {
+				ids = append(ids, r.ID)
+				return false
+			})).To(Succeed())
+			Expect(ids).To(Equal([]int64{1}))
+		})
+
+		It("rejects IDs that do not increase", func() {
+			ring := history.NewRing(nil)
+			Expect(ring.Append(record(2))).To(Succeed())
+			Expect(ring.Append(record(2))).NotTo(Succeed())
+			Expect(ring.Append(record(1))).NotTo(Succeed())
+			Expect(ring.Append(record(3))).To(Succeed())
+			Expect(replayIDs(ring, 0)).To(Equal([]int64{2, 3}))
+		})
+	})
+
+	Describe("SegmentLog", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+		})
+
+		It("replays records after reopening", func() {
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			for i := int64(1); i <= 3; i++ {
+				Expect(log.Append(record(i))).To(Succeed())
+			}
+			Expect(log.Close()).To(Succeed())
+
+			log, err = history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			Expect(log.Append(record(4))).To(Succeed())
+
+			var data []string
+			Expect(log.Replay(0, func(r history.Record) bool {
+				data = append(data, string(r.Data))
+				return true
+			})).To(Succeed())
+			Expect(data).To(Equal([]string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`}))
+		})
+
+		It("drops a partial line left by a crash when reopening", func() {
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(log.Append(record(1))).To(Succeed())
+			Expect(log.Close()).To(Succeed())
+
+			segments, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(segments).To(HaveLen(1))
+			f, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0o600)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.WriteString(`{"id":2,"data":{"i`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			log, err = history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			Expect(log.Append(record(3))).To(Succeed())
+			Expect(replayIDs(log, 0)).To(Equal([]int64{1, 3}))
+		})
+
+		It("keeps the raw lines of records", func() {
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			Expect(log.Append(history.Record{ID: 1, Data: []byte(`{"id":1}`), Raw: "raw line"})).To(Succeed())
+
+			var records []history.Record
+			Expect(log.Replay(0, func(r history.Record) bool {
+				records = append(records, r)
+				return true
+			})).To(Succeed())
+			Expect(records).To(Equal([]history.Record{{ID: 1, Data: []byte(`{"id":1}`), Raw: "raw line"}}))
+		})
+
+		It("rotates segments and deletes the oldest beyond MaxSegments", func() {
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{
+				Dir:          dir,
+				SegmentBytes: 1, // every record starts a new segment
+				MaxSegments:  2,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			for i := int64(1); i <= 4; i++ {
+				Expect(log.Append(record(i))).To(Succeed())
+			}
+
+			files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(2))
+			Expect(replayIDs(log, 0)).To(Equal([]int64{3, 4}))
+		})
+
+		It("replays only records newer than the given ID across segments", func() {
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{
+				Dir:          dir,
+				SegmentBytes: 30,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			for i := int64(1); i <= 6; i++ {
+				Expect(log.Append(record(i))).To(Succeed())
+			}
+			Expect(replayIDs(log, 3)).To(Equal([]int64{4, 5, 6}))
+		})
+
+		It("ignores unrelated files in the directory", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "README.txt"), []byte("hello"), 0o600)).To(Succeed())
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			Expect(replayIDs(log, 0)).To(BeEmpty())
+		})
+
+		It("rejects IDs that do not increase, also after reopening", func() {
+			log, err := history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(log.Append(record(2))).To(Succeed())
+			Expect(log.Append(record(1))).NotTo(Succeed())
+			Expect(log.Close()).To(Succeed())
+
+			log, err = history.OpenSegmentLog(&history.SegmentLogOptions{Dir: dir})
+			Expect(err).NotTo(HaveOccurred())
+			defer log.Close()
+			Expect(log.Append(record(2))).NotTo(Succeed())
+			Expect(log.Append(record(3))).To(Succeed())
+			Expect(replayIDs(log, 0)).To(Equal([]int64{2, 3}))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package history
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultMaxEntries is the default number of records kept by a Ring
+const DefaultMaxEntries = 100000
+
+var _ Store = &Ring{}
+
+// RingOptions configures the capacity of a Ring
+// Zero values mean no limit for the corresponding dimension
+type RingOptions struct {
+	MaxEntries int   // maximum number of records kept
+	MaxBytes   int64 // maximum total size of record data and raw lines in bytes
+}
+
+// Ring is an in-memory Store that drops the oldest records once it exceeds its capacity
+type Ring struct {
+	mu         sync.RWMutex
+	records    []Record // circular buffer
+	head       int      // index of the oldest record
+	size       int      // number of records in the buffer
+	bytes      int64    // total size of record data and raw lines in the buffer
+	lastID     int64    // ID of the last appended record
+	maxEntries int
+	maxBytes   int64
+}
+
+// NewRing creates a new in-memory ring buffer store
+func NewRing(options *RingOptions) *Ring {
+	r := &Ring{}
+	if options != nil {
+		r.maxEntries = options.MaxEntries
+		r.maxBytes = options.MaxBytes
+	}
+	return r
+}
+
+// Append adds a record, evicting the oldest records when a limit is exceeded
+func (r *Ring) Append(record Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := checkID(record.ID, r.lastID); err != nil {
+		return err
+	}
+	r.lastID = record.ID
+	if r.maxEntries > 0 && r.size == r.maxEntries {
+		r.dropOldest()
+	}
+	if r.size == len(r.records) {
+		r.grow()
+	}
+	r.records[(r.head+r.size)%len(r.records)] = record
+	r.size++
+	r.bytes += recordBytes(record)
+
+	// keep at least the newest record even if it alone exceeds the byte limit
+	for r.maxBytes > 0 && r.bytes > r.maxBytes && r.size > 1 {
+		r.dropOldest()
+	}
+	return nil
+}
+
+// Replay calls fn for records newer than after
+// Records are copied out under the lock so that slow consumers do not block Append
+func (r *Ring) Replay(after int64, fn func(Record) bool) error {
+	r.mu.RLock()
+	// IDs strictly increase, as Append ensures, so binary search for the first newer record
+	start := sort.Search(r.size, func(i int) bool {
+		return r.at(i).ID > after
+	})
+	pending := make([]Record, 0, r.size-start)
+	for i := start; i < r.size; i++ {
+		pending = append(pending, r.at(i))
+	}
+	r.mu.RUnlock()
+
+	for _, record := range pending {
+		if !fn(record) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Len returns the number of records currently kept
+func (r *Ring) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.size
+}
+
+// Bytes returns the total size of record data and raw lines currently kept
+func (r *Ring) Bytes() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.bytes
+}
+
+// Close is a no-op for the in-memory store
+func (r *Ring) Close() error {
+	return nil
+}
+
+// at returns the i-th oldest record
+func (r *Ring) at(i int) Record {
+	return r.records[(r.head+i)%len(r.records)]
+}
+
+func (r *Ring) dropOldest() {
+	r.bytes -= recordBytes(r.records[r.head])
+	r.records[r.head] = Record{} // release data for GC
+	r.head = (r.head + 1) % len(r.records)
+	r.size--
+}
+
+// recordBytes is the size of a record counted against maxBytes
+func recordBytes(record Record) int64 {
+	return int64(len(record.Data) + len(record.Raw))
+}
+
+// grow doubles the buffer capacity, bounded by maxEntries
+func (r *Ring) grow() {
+	capacity := len(r.records) * 2
+	if capacity == 0 {
+		capacity = 64
+	}
+	if r.maxEntries > 0 && capacity > r.maxEntries {
+		capacity = r.maxEntries
+	}
+	records := make([]Record, capacity)
+	for i := 0; i < r.size; i++ {
+		records[i] = r.at(i)
+	}
+	r.records = records
+	r.head = 0
+}
@@ -0,0 +1,275 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultSegmentBytes is the default size at which a new segment file is started
+	DefaultSegmentBytes = 16 << 20
+	// DefaultMaxSegments is the default number of segment files kept on disk
+	DefaultMaxSegments = 8
+
+	segmentExt = ".jsonl"
+)
+
+var _ Store = &SegmentLog{}
+
+// SegmentLogOptions configures an on-disk SegmentLog
+type SegmentLogOptions struct {
+	Dir          string // directory holding segment files
+	SegmentBytes int64  // size at which the active segment is rotated
+	MaxSegments  int    // number of segments kept; older ones are deleted
+}
+
+// SegmentLog is a Store that persists records to a directory of append-only segment files
+// Each segment is a JSON-lines file named after the ID of its first record,
+// so history survives restarts and can be replayed without loading it into memory
+type SegmentLog struct {
+	mu           sync.Mutex
+	dir          string
+	segmentBytes int64
+	maxSegments  int
+	segments     []int64  // first record IDs of segments, oldest first
+	active       *os.File // segment currently appended to
+	activeBytes  int64
+	lastID       int64 // ID of the last appended record
+}
+
+// segmentLine is the on-disk representation of a Record
+type segmentLine struct {
+	ID   int64           `json:"id"`
+	Data json.RawMessage `json:"data"`
+	Raw  string          `json:"raw,omitempty"`
+}
+
+// OpenSegmentLog opens or creates a segment log in the configured directory
+func OpenSegmentLog(options *SegmentLogOptions) (*SegmentLog, error) {
+	l := &SegmentLog{
+		dir:          options.Dir,
+		segmentBytes: options.SegmentBytes,
+		maxSegments:  options.MaxSegments,
+	}
+	if l.segmentBytes <= 0 {
+		l.segmentBytes = DefaultSegmentBytes
+	}
+	if l.maxSegments <= 0 {
+		l.maxSegments = DefaultMaxSegments
+	}
+
+	if err := os.MkdirAll(l.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	files, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue // not a segment file
+		}
+		l.segments = append(l.segments, id)
+	}
+	sort.Slice(l.segments, func(i, j int) bool { return l.segments[i] < l.segments[j] })
+
+	// continue appending to the newest segment if it still has room
+	if n := len(l.segments); n > 0 {
+		if err := l.loadLastID(l.segments[n-1]); err != nil {
+			return nil, err
+		}
+		path := l.segmentPath(l.segments[n-1])
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat segment: %w", err)
+		}
+		if info.Size() < l.segmentBytes {
+			f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o600)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open segment: %w", err)
+			}
+			// drop a partial line left by a crash, which the next record would be appended to
+			size, err := completeLength(f, info.Size())
+			if err == nil && size < info.Size() {
+				err = f.Truncate(size)
+			}
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to repair segment: %w", err)
+			}
+			l.active = f
+			l.activeBytes = size
+		}
+	}
+	return l, nil
+}
+
+// Append writes a record to the active segment, rotating segments when it is full
+func (l *SegmentLog) Append(record Record) error {
+	line, err := json.Marshal(segmentLine{ID: record.ID, Data: record.Data, Raw: record.Raw})
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := checkID(record.ID, l.lastID); err != nil {
+		return err
+	}
+	if l.active == nil || l.activeBytes >= l.segmentBytes {
+		if err := l.rotate(record.ID); err != nil {
+			return err
+		}
+	}
+	n, err := l.active.Write(line)
+	l.activeBytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	l.lastID = record.ID
+	return nil
+}
+
+// Replay reads records newer than after from disk, oldest segment first
+func (l *SegmentLog) Replay(after int64, fn func(Record) bool) error {
+	l.mu.Lock()
+	segments := append([]int64(nil), l.segments...)
+	l.mu.Unlock()
+
+	for i, first := range segments {
+		// skip segments whose records are all older than after
+		// IDs strictly increase, so every record is older than the first ID of the next segment
+		if i+1 < len(segments) && segments[i+1] <= after+1 {
+			continue
+		}
+		more, err := l.replaySegment(first, after, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Close closes the active segment
+func (l *SegmentLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active == nil {
+		return nil
+	}
+	err := l.active.Close()
+	l.active = nil
+	return err
+}
+
+// replaySegment calls fn for records in a single segment
+// It returns false when fn asked to stop
+func (l *SegmentLog) replaySegment(first int64, after int64, fn func(Record) bool) (bool, error) {
+	f, err := os.Open(l.segmentPath(first))
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil // deleted by retention while replaying
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open segment: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// a line without newline is still being written; ignore it
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read segment: %w", err)
+		}
+		var decoded segmentLine
+		if err := json.Unmarshal(bytes.TrimSpace(line), &decoded); err != nil {
+			continue // skip corrupted lines, e.g. after a crash
+		}
+		if decoded.ID <= after {
+			continue
+		}
+		if !fn(Record{ID: decoded.ID, Data: []byte(decoded.Data), Raw: decoded.Raw}) {
+			return false, nil
+		}
+	}
+}
+
+// rotate starts a new segment beginning with the given record ID and enforces retention
+func (l *SegmentLog) rotate(firstID int64) error {
+	if l.active != nil {
+		if err := l.active.Close(); err != nil {
+			return fmt.Errorf("failed to close segment: %w", err)
+		}
+		l.active = nil
+	}
+	f, err := os.OpenFile(l.segmentPath(firstID), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+	l.active = f
+	l.activeBytes = 0
+	l.segments = append(l.segments, firstID)
+
+	for len(l.segments) > l.maxSegments {
+		if err := os.Remove(l.segmentPath(l.segments[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove segment: %w", err)
+		}
+		l.segments = l.segments[1:]
+	}
+	return nil
+}
+
+// loadLastID reads the ID of the last record of the newest segment, so that appending continues after it
+func (l *SegmentLog) loadLastID(newest int64) error {
+	// an empty segment follows the records of the one before it
+	l.lastID = newest - 1
+	_, err := l.replaySegment(newest, 0, func(record Record) bool {
+		l.lastID = record.ID
+		return true
+	})
+	return err
+}
+
+// completeLength returns the length of a segment up to the newline ending its last line
+func completeLength(f *os.File, size int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		n, err := f.ReadAt(buf[:end-start], start)
+		if err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+func (l *SegmentLog) segmentPath(firstID int64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", firstID, segmentExt))
+}
@@ -0,0 +1,242 @@
+package klog
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// headerRegex matches the klog header: Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg
+var headerRegex = regexp.MustCompile(`^([IWEF])(\d{2})(\d{2}) (\d{2}):(\d{2}):(\d{2})\.(\d{6})\s+(\d+) ([^ \]]+:\d+)\] ?(.*)$`)
+
+var _ receriver.Parser = &Parser{}
+
+// Parser parses log lines written by k8s.io/klog, e.g.
+// I1018 10:00:00.123456   12345 controller.go:123] "msg" key="value"
+type Parser struct {
+	now func() time.Time // used to infer the year, which klog does not print
+}
+
+func NewParser() *Parser {
+	return &Parser{now: time.Now}
+}
+
+func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	m := headerRegex.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("invalid log format: missing klog header")
+	}
+
+	level, err := ParseLevel(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse level: %w", err)
+	}
+	timestamp, err := p.parseTimestamp(m[2:8])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	pid, err := strconv.Atoi(m[8])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pid: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"pid":    pid,
+		"caller": m[9],
+	}
+	message := m[10]
+
+	// structured logs (InfoS, ErrorS) quote the message and append key=value pairs
+	if strings.HasPrefix(message, `"`) {
+		msg, rest, err := readQuoted(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message: %w", err)
+		}
+		consumed := false
+		consume := func() {
+			consumed = true
+			consumeLine()
+		}
+		// once lines of a multi-line value are consumed, other parsers cannot see them,
+		// so the entry is kept with the pairs parsed so far
+		if err := parsePairs(rest, data, peekLine, consume); err != nil && !consumed {
+			return nil, fmt.Errorf("failed to parse key/value pairs: %w", err)
+		}
+		message = msg
+	}
+
+	return []*entry.Entry{{
+		Structured: &entry.Structured{
+			Timestamp: timestamp,
+			Level:     level,
+			Message:   message,
+			Data:      data,
+		},
+	}}, nil
+}
+
+// ParseLevel converts klog severity character to entry.Level
+func ParseLevel(severity string) (entry.Level, error) {
+	switch severity {
+	case "I":
+		return entry.LevelInfo, nil
+	case "W":
+		return entry.LevelWarning, nil
+	case "E":
+		return entry.LevelError, nil
+	case "F":
+		return entry.LevelFatal, nil
+	default:
+		return "", fmt.Errorf("unknown level: %s", severity)
+	}
+}
+
+// parseTimestamp builds the timestamp from month, day, hour, minute, second and microsecond fields
+// klog omits the year, so the current year is assumed unless that would put the entry
+// in the future, which happens when reading December logs in January
+func (p *Parser) parseTimestamp(fields []string) (time.Time, error) {
+	var values [6]int
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return time.Time{}, err
+		}
+		values[i] = v
+	}
+	now := p.now()
+	timestamp := time.Date(now.Year(), time.Month(values[0]), values[1],
+		values[2], values[3], values[4], values[5]*1000, time.Local)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	return timestamp, nil
+}
+
+// parsePairs parses klog key=value pairs into data
+// Multi-line values are written as key=<, followed by tab-indented lines and a closing " >" line
+func parsePairs(s string, data map[string]interface{}, peekLine func() (string, error), consumeLine func()) error {
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], ` "`) {
+			return fmt.Errorf("expected key=value at %q", s)
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		var value interface{}
+		var err error
+		switch {
+		case s == "<":
+			value, s, err = readMultiline(peekLine, consumeLine)
+		case strings.HasPrefix(s, `"`):
+			value, s, err = readQuoted(s)
+		case strings.HasPrefix(s, "{"), strings.HasPrefix(s, "["):
+			value, s, err = readBracketed(s)
+		default:
+			end := strings.IndexByte(s, ' ')
+			if end == -1 {
+				end = len(s)
+			}
+			value, s = parseScalar(s[:end]), s[end:]
+		}
+		if err != nil {
+			return err
+		}
+		data[key] = value
+	}
+}
+
+// readQuoted reads a Go quoted string from the beginning of s and returns the unquoted value and the rest
+func readQuoted(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // skip escaped character
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", err
+			}
+			return value, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted string: %s", s)
+}
+
+// readBracketed reads a {...} or [...] value, e.g. a struct printed with %+v, keeping it as text
+func readBracketed(s string) (string, string, error) {
+	depth := 0
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote && c == '\\':
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return s[:i+1], s[i+1:], nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unterminated value: %s", s)
+}
+
+// readMultiline reads the lines of a multi-line value following a key=< line
+// It returns the value and the remainder of the closing line
+// Only the tab-indented lines of the value are consumed, so a value left unterminated,
+// e.g. by a crash, ends before the next line instead of swallowing the rest of the stream
+func readMultiline(peekLine func() (string, error), consumeLine func()) (string, string, error) {
+	var lines []string
+	for peekLine != nil {
+		next, err := peekLine()
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(next, " >") {
+			consumeLine()
+			return strings.Join(lines, "\n"), next[2:], nil
+		}
+		if !strings.HasPrefix(next, "\t") {
+			break
+		}
+		consumeLine()
+		lines = append(lines, next[1:])
+	}
+	if lines == nil {
+		return "", "", fmt.Errorf("unterminated multi-line value")
+	}
+	return strings.Join(lines, "\n"), "", nil
+}
+
+// parseScalar converts an unquoted value to a number, boolean or nil when possible
+func parseScalar(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "<nil>":
+		return nil
+	}
+	// NaN and Inf cannot be encoded as JSON, so they are kept as text
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	return s
+}
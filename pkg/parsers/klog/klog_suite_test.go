@@ -0,0 +1,13 @@
+package klog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Klog Suite")
+}
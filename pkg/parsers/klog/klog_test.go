@@ -0,0 +1,204 @@
+package klog_test
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/parsers/klog"
+)
+
+var _ = Describe("Klog", func() {
+	Describe("ParseLevel", func() {
+		DescribeTable("parsing severities",
+			func(input string, expected entry.Level, expectError bool) {
+				level, err := klog.ParseLevel(input)
+
+				if expectError {
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("unknown level"))
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(level).To(Equal(expected))
+				}
+			},
+			Entry("info", "I", entry.LevelInfo, false),
+			Entry("warning", "W", entry.LevelWarning, false),
+			Entry("error", "E", entry.LevelError, false),
+			Entry("fatal", "F", entry.LevelFatal, false),
+			Entry("unknown", "X", entry.Level(""), true),
+		)
+	})
+
+	Describe("Parser", func() {
+		var parser *klog.Parser
+
+		BeforeEach(func() {
+			parser = klog.NewParser()
+		})
+
+		// linesAfter serves lines to the parser, counting those it consumes
+		linesAfter := func(lines ...string) (func() (string, error), func(), func() int) {
+			var current int
+			peekLine := func() (string, error) {
+				if current >= len(lines) {
+					return "", io.EOF
+				}
+				return lines[current], nil
+			}
+			return peekLine, func() { current++ }, func() int { return current }
+		}
+
+		Context("structured log line", func() {
+			It("parses message and key/value pairs", func() {
+				input := `I0130 15:52:37.123456   12345 controller.go:123] "Reconciling" pod="default/nginx" attempt=3 ready=true obj={Name:nginx Namespace:default} err=<nil>`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.LevelInfo))
+				Expect(e.Structured.Message).To(Equal("Reconciling"))
+				Expect(e.Structured.Timestamp.Month()).To(Equal(time.January))
+				Expect(e.Structured.Timestamp.Day()).To(Equal(30))
+				Expect(e.Structured.Timestamp.Hour()).To(Equal(15))
+				Expect(e.Structured.Timestamp.Nanosecond()).To(Equal(123456000))
+				Expect(e.Structured.Data).To(Equal(map[string]interface{}{
+					"pid":     12345,
+					"caller":  "controller.go:123",
+					"pod":     "default/nginx",
+					"attempt": float64(3),
+					"ready":   true,
+					"obj":     "{Name:nginx Namespace:default}",
+					"err":     nil,
+				}))
+			})
+
+			It("unescapes quoted values", func() {
+				input := `E0130 15:52:37.123456       1 reflector.go:10] "Failed to watch" err="failed to list \"pods\": forbidden"`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.LevelError))
+				Expect(e.Structured.Data).To(HaveKeyWithValue("err", `failed to list "pods": forbidden`))
+			})
+
+			It("reads multi-line values", func() {
+				input := `I0130 15:52:37.123456       1 main.go:10] "Config loaded" config=<`
+				peekLine, consumeLine, consumed := linesAfter(
+					"\tapiVersion: v1",
+					"\tkind: Config",
+					" > source=\"file\"",
+					"I0130 15:52:38.000000       1 main.go:11] next",
+				)
+
+				entries, err := parser.Parse(input, peekLine, consumeLine)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(consumed()).To(Equal(3))
+				e := entries[0]
+				Expect(e.Structured.Data).To(HaveKeyWithValue("config", "apiVersion: v1\nkind: Config"))
+				Expect(e.Structured.Data).To(HaveKeyWithValue("source", "file"))
+			})
+
+			It("ends unterminated multi-line values before the next line", func() {
+				input := `I0130 15:52:37.123456       1 main.go:10] "Config loaded" config=<`
+				peekLine, consumeLine, consumed := linesAfter(
+					"\tapiVersion: v1",
+					"I0130 15:52:38.000000       1 main.go:11] next",
+				)
+
+				entries, err := parser.Parse(input, peekLine, consumeLine)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(consumed()).To(Equal(1))
+				Expect(entries[0].Structured.Message).To(Equal("Config loaded"))
+				Expect(entries[0].Structured.Data).To(HaveKeyWithValue("config", "apiVersion: v1"))
+			})
+
+			It("keeps multi-line values cut off by the end of the stream", func() {
+				input := `I0130 15:52:37.123456       1 main.go:10] "Config loaded" config=<`
+				peekLine, consumeLine, consumed := linesAfter("\tapiVersion: v1", "\tkind: Config")
+
+				entries, err := parser.Parse(input, peekLine, consumeLine)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(consumed()).To(Equal(2))
+				Expect(entries[0].Structured.Data).To(HaveKeyWithValue("config", "apiVersion: v1\nkind: Config"))
+			})
+
+			It("keeps the entry when pairs after a multi-line value are broken", func() {
+				input := `I0130 15:52:37.123456       1 main.go:10] "Config loaded" config=<`
+				peekLine, consumeLine, consumed := linesAfter("\tkind: Config", " > not a pair")
+
+				entries, err := parser.Parse(input, peekLine, consumeLine)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(consumed()).To(Equal(2))
+				Expect(entries[0].Structured.Data).To(HaveKeyWithValue("config", "kind: Config"))
+			})
+		})
+
+		Context("unstructured log line", func() {
+			It("keeps the text as message", func() {
+				input := `W0130 15:52:37.123456   12345 warnings.go:70] metadata.name: this is deprecated`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.LevelWarning))
+				Expect(e.Structured.Message).To(Equal("metadata.name: this is deprecated"))
+				Expect(e.Structured.Data).To(Equal(map[string]interface{}{
+					"pid":    12345,
+					"caller": "warnings.go:70",
+				}))
+			})
+		})
+
+		Context("year inference", func() {
+			It("uses the current year for recent entries", func() {
+				now := time.Now()
+				input := fmt.Sprintf("I%s 00:00:00.000000 1 main.go:1] hello", now.Format("0102"))
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries[0].Structured.Timestamp.Year()).To(Equal(now.Year()))
+			})
+
+			It("uses the previous year for entries that would be in the future", func() {
+				now := time.Now()
+				future := now.AddDate(0, 0, 3)
+				input := fmt.Sprintf("I%s 00:00:00.000000 1 main.go:1] hello", future.Format("0102"))
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries[0].Structured.Timestamp.Year()).To(Equal(future.Year() - 1))
+			})
+		})
+
+		Context("error cases", func() {
+			DescribeTable("rejects lines that are not klog",
+				func(input string) {
+					_, err := parser.Parse(input, nil, nil)
+					Expect(err).To(HaveOccurred())
+				},
+				Entry("plain text", "starting manager"),
+				Entry("logr format", "2025-01-30T15:52:37+09:00\tINFO\tsetup\tstarting manager"),
+				Entry("unterminated message", `I0130 15:52:37.123456 1 main.go:1] "hello`),
+				Entry("broken pair", `I0130 15:52:37.123456 1 main.go:1] "hello" not a pair`),
+				Entry("unterminated multi-line value", `I0130 15:52:37.123456 1 main.go:1] "hello" config=<`),
+			)
+		})
+	})
+})
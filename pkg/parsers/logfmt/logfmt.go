@@ -0,0 +1,232 @@
+package logfmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+var (
+	timestampKeys = []string{"time", "ts", "timestamp"}
+	levelKeys     = []string{"level", "lvl", "severity"}
+	messageKeys   = []string{"msg", "message"}
+	stackKeys     = []string{"stacktrace", "stack"}
+)
+
+// timestampLayouts are the layouts tried when parsing a timestamp value
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02 15:04:05.999999999",
+}
+
+var _ receriver.Parser = &Parser{}
+
+// Parser parses logfmt lines, e.g.
+// time=2025-01-30T15:52:37Z level=info msg="starting manager" controller=machine
+type Parser struct {
+}
+
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	pairs, err := Decode(line)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode logfmt: %w", err)
+	}
+
+	data := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		data[pair.Key] = pair.Value
+	}
+
+	rawLevel, ok := take(data, levelKeys)
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing level")
+	}
+	message, ok := take(data, messageKeys)
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing message")
+	}
+	level, err := ParseLevel(rawLevel)
+	if err != nil {
+		// keep the line structured, using the level as written
+		level = entry.Level(strings.ToLower(rawLevel))
+	}
+
+	// logfmt lines commonly omit the timestamp, in which case the time of reading is used
+	timestamp, readTime := time.Now(), true
+	if rawTimestamp, ok := take(data, timestampKeys); ok {
+		if timestamp, err = ParseTimestamp(rawTimestamp); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+		readTime = false
+	}
+	stack, _ := take(data, stackKeys)
+
+	return []*entry.Entry{{
+		Structured: &entry.Structured{
+			Timestamp: timestamp,
+			ReadTime:  readTime,
+			Level:     level,
+			Message:   message,
+			Data:      data,
+			Stack:     stack,
+		},
+	}}, nil
+}
+
+// Pair is a single key=value pair of a logfmt line
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Decode splits a logfmt line into key/value pairs, unquoting quoted values
+// A key without "=" has an empty value
+func Decode(line string) ([]Pair, error) {
+	var pairs []Pair
+	s := line
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return pairs, nil
+		}
+
+		end := strings.IndexAny(s, "= \t")
+		if end == -1 {
+			end = len(s)
+		}
+		key := s[:end]
+		if key == "" || strings.ContainsRune(key, '"') {
+			return nil, fmt.Errorf("invalid key at position %d", len(line)-len(s))
+		}
+		s = s[end:]
+
+		var value string
+		if strings.HasPrefix(s, "=") {
+			s = s[1:]
+			if strings.HasPrefix(s, `"`) {
+				var err error
+				value, s, err = readQuoted(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for %q: %w", key, err)
+				}
+				if s != "" && s[0] != ' ' && s[0] != '\t' {
+					return nil, fmt.Errorf("unexpected character after value for %q", key)
+				}
+			} else {
+				end := strings.IndexAny(s, " \t")
+				if end == -1 {
+					end = len(s)
+				}
+				value = s[:end]
+				if strings.ContainsRune(value, '"') {
+					return nil, fmt.Errorf("invalid value for %q", key)
+				}
+				s = s[end:]
+			}
+		}
+		pairs = append(pairs, Pair{Key: key, Value: value})
+	}
+}
+
+// ParseLevel converts a logfmt level value to entry.Level
+func ParseLevel(level string) (entry.Level, error) {
+	return entry.ParseLevel(level)
+}
+
+// ParseTimestamp parses a logfmt timestamp value
+func ParseTimestamp(value string) (time.Time, error) {
+	var err error
+	for _, layout := range timestampLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// take removes and returns the value of the first of keys present in data
+func take(data map[string]interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := data[key]; ok {
+			delete(data, key)
+			return value.(string), true
+		}
+	}
+	return "", false
+}
+
+// readQuoted reads a quoted value from the beginning of s and returns the unescaped value and the rest
+// Escapes follow JSON string syntax, which is what common logfmt encoders produce,
+// plus the Go escapes written by strconv.Quote (e.g. log/slog's TextHandler)
+func readQuoted(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); {
+		c := s[i]
+		switch c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", fmt.Errorf("unterminated escape sequence")
+			}
+			switch esc := s[i+1]; esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'a':
+				b.WriteByte('\a')
+			case 'v':
+				b.WriteByte('\v')
+			case 'x', 'u', 'U':
+				digits := 2
+				switch esc {
+				case 'u':
+					digits = 4
+				case 'U':
+					digits = 8
+				}
+				if i+2+digits > len(s) {
+					return "", "", fmt.Errorf("invalid escape sequence \\%c", esc)
+				}
+				v, err := strconv.ParseUint(s[i+2:i+2+digits], 16, 32)
+				if err != nil {
+					return "", "", fmt.Errorf("invalid escape sequence: %w", err)
+				}
+				if esc == 'x' {
+					b.WriteByte(byte(v))
+				} else {
+					b.WriteRune(rune(v))
+				}
+				i += digits
+			default:
+				return "", "", fmt.Errorf("invalid escape sequence \\%c", esc)
+			}
+			i += 2
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			b.WriteRune(r)
+			i += size
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value")
+}
@@ -0,0 +1,13 @@
+package logfmt_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogfmt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logfmt Suite")
+}
@@ -0,0 +1,112 @@
+package logfmt_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/parsers/logfmt"
+)
+
+var _ = Describe("Logfmt", func() {
+	Describe("Decode", func() {
+		DescribeTable("decoding pairs",
+			func(input string, expected []logfmt.Pair) {
+				pairs, err := logfmt.Decode(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pairs).To(Equal(expected))
+			},
+			Entry("bare values", "a=1 b=two", []logfmt.Pair{{Key: "a", Value: "1"}, {Key: "b", Value: "two"}}),
+			Entry("quoted value", `msg="hello world"`, []logfmt.Pair{{Key: "msg", Value: "hello world"}}),
+			Entry("escaped quote", `msg="say \"hi\""`, []logfmt.Pair{{Key: "msg", Value: `say "hi"`}}),
+			Entry("escape sequences", `msg="a\nb\tc\\dé"`, []logfmt.Pair{{Key: "msg", Value: "a\nb\tc\\dé"}}),
+			Entry("Go escape sequences", `msg="\x1b[0m\u00e9\U0001F600"`, []logfmt.Pair{{Key: "msg", Value: "\x1b[0mé😀"}}),
+			Entry("empty quoted value", `a=""`, []logfmt.Pair{{Key: "a", Value: ""}}),
+			Entry("empty bare value", `a= b=1`, []logfmt.Pair{{Key: "a", Value: ""}, {Key: "b", Value: "1"}}),
+			Entry("key without value", `debug a=1`, []logfmt.Pair{{Key: "debug", Value: ""}, {Key: "a", Value: "1"}}),
+			Entry("equals sign in bare value", `url=http://x/?a=b`, []logfmt.Pair{{Key: "url", Value: "http://x/?a=b"}}),
+			Entry("extra whitespace", "  a=1 \t b=2  ", []logfmt.Pair{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}),
+		)
+
+		DescribeTable("rejecting malformed input",
+			func(input string) {
+				_, err := logfmt.Decode(input)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("unterminated quote", `msg="hello`),
+			Entry("invalid escape", `msg="\q"`),
+			Entry("quote in key", `"msg"=hello`),
+			Entry("quote in bare value", `msg=he"llo`),
+			Entry("garbage after quoted value", `msg="hello"world`),
+		)
+	})
+
+	Describe("Parser", func() {
+		var parser *logfmt.Parser
+
+		BeforeEach(func() {
+			parser = logfmt.NewParser()
+		})
+
+		It("parses a logfmt line", func() {
+			input := `time=2025-01-30T15:52:37.5+09:00 level=warn msg="cache miss" controller=machine key="default/m-1"`
+
+			entries, err := parser.Parse(input, nil, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+
+			e := entries[0]
+			Expect(e.Structured.Timestamp.UTC()).To(Equal(time.Date(2025, 1, 30, 6, 52, 37, 500000000, time.UTC)))
+			Expect(e.Structured.ReadTime).To(BeFalse())
+			Expect(e.Structured.Level).To(Equal(entry.LevelWarning))
+			Expect(e.Structured.Message).To(Equal("cache miss"))
+			Expect(e.Structured.Data).To(Equal(map[string]interface{}{
+				"controller": "machine",
+				"key":        "default/m-1",
+			}))
+		})
+
+		It("accepts alternative key names", func() {
+			input := `ts=2025-01-30T06:52:37Z lvl=ERROR message=failed stacktrace="main.main\n\tmain.go:10"`
+
+			entries, err := parser.Parse(input, nil, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			e := entries[0]
+			Expect(e.Structured.Level).To(Equal(entry.LevelError))
+			Expect(e.Structured.Message).To(Equal("failed"))
+			Expect(e.Structured.Stack).To(Equal("main.main\n\tmain.go:10"))
+			Expect(e.Structured.Data).To(BeEmpty())
+		})
+
+		It("keeps unknown levels as written", func() {
+			entries, err := parser.Parse(`level=NOTICE msg=hello`, nil, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].Structured.Level).To(Equal(entry.Level("notice")))
+			Expect(entries[0].Structured.Message).To(Equal("hello"))
+		})
+
+		It("uses the current time when the timestamp is missing", func() {
+			entries, err := parser.Parse(`level=info msg=hello`, nil, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].Structured.Timestamp).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(entries[0].Structured.ReadTime).To(BeTrue())
+		})
+
+		DescribeTable("rejects lines that are not logfmt logs",
+			func(input string) {
+				_, err := parser.Parse(input, nil, nil)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("plain text", `starting "manager" now`),
+			Entry("missing level", `msg=hello a=1`),
+			Entry("missing message", `level=info a=1`),
+			Entry("invalid timestamp", `time=yesterday level=info msg=hello`),
+		)
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +15,9 @@ import (
 
 var rfc3339Regex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}`)
 
+// verbosityRegex matches V-level strings such as DEBUG(2) or LEVEL(-2)
+var verbosityRegex = regexp.MustCompile(`^(?:DEBUG\((\d+)\)|LEVEL\(-(\d+)\))$`)
+
 var _ receriver.Parser = &Parser{}
 
 type Parser struct {
@@ -28,6 +32,10 @@ func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine
 	var data map[string]interface{}
 	textPart := line
 	if idx := strings.Index(line, "{"); idx != -1 {
+		if idx == 0 {
+			// a bare JSON object is not the development console format
+			return nil, fmt.Errorf("invalid log format: missing tab")
+		}
 		jsonPart := line[idx:]
 		if err := json.Unmarshal([]byte(jsonPart), &data); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
@@ -44,7 +52,11 @@ func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine
 
 	level, err := ParseLevel(textPartTokens[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse level: %w", err)
+		if textPartTokens[1] == "" {
+			return nil, fmt.Errorf("failed to parse level: %w", err)
+		}
+		// keep the line structured, using the level as written
+		level = entry.Level(strings.ToLower(textPartTokens[1]))
 	}
 
 	timestamp, err := time.Parse(time.RFC3339, textPartTokens[0])
@@ -56,7 +68,7 @@ func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine
 
 	// collect stack trace
 	var stack string
-	if level == entry.LevelError {
+	if level.Severity() >= entry.LevelError.Severity() {
 		stack = parseStack(peekLine, consumeLine)
 	}
 
@@ -64,6 +76,7 @@ func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine
 		Structured: &entry.Structured{
 			Timestamp: timestamp,
 			Level:     level,
+			Verbosity: ParseVerbosity(textPartTokens[1]),
 			Message:   message,
 			Data:      data,
 			Stack:     stack,
@@ -72,19 +85,45 @@ func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine
 }
 
 // ParseLevel converts log level string to entry.Level
+// V-levels such as DEBUG(2) or LEVEL(-2) are reported as debug
 func ParseLevel(level string) (entry.Level, error) {
 	switch level {
 	case "INFO":
 		return entry.LevelInfo, nil
-	case "WARNING":
+	case "WARN", "WARNING":
 		return entry.LevelWarning, nil
 	case "ERROR":
 		return entry.LevelError, nil
 	case "DEBUG":
 		return entry.LevelDebug, nil
-	default:
-		return "", fmt.Errorf("unknown level: %s", level)
+	case "DPANIC", "PANIC":
+		return entry.LevelPanic, nil
+	case "FATAL":
+		return entry.LevelFatal, nil
+	}
+	if ParseVerbosity(level) > 0 {
+		return entry.LevelDebug, nil
+	}
+	return "", fmt.Errorf("unknown level: %s", level)
+}
+
+// ParseVerbosity extracts the logr V-level from a level string
+// zap prints logr V(n) as LEVEL(-n); DEBUG(n) is also accepted
+// It returns 0 for levels without verbosity
+func ParseVerbosity(level string) int {
+	m := verbosityRegex.FindStringSubmatch(level)
+	if m == nil {
+		return 0
+	}
+	digits := m[1]
+	if digits == "" {
+		digits = m[2]
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
 	}
+	return n
 }
 
 // IsStackNamish determines if a line is a function name line in stack trace
@@ -96,7 +135,7 @@ func IsStackNamish(line string) bool {
 	// verify it's not a date (RFC3339)
 	if strings.HasPrefix(line, "2") { // starts with 2XXX
 		// check for date-like pattern
-		if line[4] == '-' && line[7] == '-' && line[10] == 'T' {
+		if len(line) > 10 && line[4] == '-' && line[7] == '-' && line[10] == 'T' {
 			// verify if it matches RFC3339 format
 			if rfc3339Regex.MatchString(line) {
 				return false
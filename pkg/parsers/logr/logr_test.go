@@ -29,10 +29,28 @@ var _ = Describe("Logr", func() {
 			Entry("WARNING level", "WARNING", entry.LevelWarning, false, ""),
 			Entry("ERROR level", "ERROR", entry.LevelError, false, ""),
 			Entry("DEBUG level", "DEBUG", entry.LevelDebug, false, ""),
+			Entry("WARN level", "WARN", entry.LevelWarning, false, ""),
+			Entry("DPANIC level", "DPANIC", entry.LevelPanic, false, ""),
+			Entry("PANIC level", "PANIC", entry.LevelPanic, false, ""),
+			Entry("FATAL level", "FATAL", entry.LevelFatal, false, ""),
+			Entry("DEBUG V-level", "DEBUG(2)", entry.LevelDebug, false, ""),
+			Entry("zap V-level", "LEVEL(-3)", entry.LevelDebug, false, ""),
 			Entry("unknown level", "UNKNOWN", entry.Level(""), true, "unknown level: UNKNOWN"),
 		)
 	})
 
+	Describe("ParseVerbosity", func() {
+		DescribeTable("extracting V-levels",
+			func(input string, expected int) {
+				Expect(logr.ParseVerbosity(input)).To(Equal(expected))
+			},
+			Entry("DEBUG V-level", "DEBUG(2)", 2),
+			Entry("zap V-level", "LEVEL(-3)", 3),
+			Entry("plain DEBUG", "DEBUG", 0),
+			Entry("INFO", "INFO", 0),
+		)
+	})
+
 	Describe("IsStackNamish", func() {
 		DescribeTable("identifying stack trace lines",
 			func(input string, expected bool) {
@@ -48,6 +66,9 @@ var _ = Describe("Logr", func() {
 			Entry("RFC3339 timestamp line",
 				"2025-01-30T15:52:37+09:00\tINFO\tsetup\tstarting manager",
 				false),
+			Entry("short line starting with 2",
+				"200",
+				false),
 			Entry("indented line",
 				"        /path/to/file.go:123",
 				false),
@@ -125,6 +146,31 @@ var _ = Describe("Logr", func() {
 			})
 		})
 
+		Context("error log followed by a short line", func() {
+			It("does not take the line as a stack", func() {
+				input := "2025-01-30T15:53:07+09:00\tERROR\tsetup\tfailed"
+				peekLines := []string{"200"}
+
+				var currentLine int
+				peekLine := func() (string, error) {
+					if currentLine >= len(peekLines) {
+						return "", io.EOF
+					}
+					return peekLines[currentLine], nil
+				}
+				consumeLine := func() {
+					currentLine++
+				}
+
+				entries, err := parser.Parse(input, peekLine, consumeLine)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Structured.Stack).To(BeEmpty())
+				Expect(currentLine).To(Equal(0))
+			})
+		})
+
 		Context("log line without metadata (multiple spaces)", func() {
 			It("parses successfully", func() {
 				input := "2025-01-30T15:52:37+09:00\tINFO\tsetup\tstarting manager"
@@ -241,6 +287,62 @@ other.example.com/path/to/file.(*Struct).Method
 			})
 		})
 
+		Context("log line with V-level", func() {
+			It("preserves the verbosity", func() {
+				input := "2025-01-30T15:52:37+09:00\tDEBUG(2)\tcontroller\tpolling"
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.LevelDebug))
+				Expect(e.Structured.Verbosity).To(Equal(2))
+				Expect(e.Structured.Message).To(Equal("controller\tpolling"))
+			})
+		})
+
+		Context("log line with unknown level", func() {
+			It("keeps the line structured with the level as written", func() {
+				input := "2025-01-30T15:52:37+09:00\tNOTICE\tsetup\tstarting manager"
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.Level("notice")))
+				Expect(e.Structured.Message).To(Equal("setup\tstarting manager"))
+			})
+		})
+
+		Context("fatal log with stack trace", func() {
+			It("collects the stack trace", func() {
+				input := "2025-01-30T15:53:07+09:00\tFATAL\tsetup\tproblem running manager"
+				peekLines := []string{
+					"sigs.k8s.io/controller-runtime/pkg/manager.(*controllerManager).Start",
+					"\t/go/pkg/mod/sigs.k8s.io/controller-runtime@v0.19.0/pkg/manager/internal.go:42",
+				}
+
+				var currentLine int
+				peekLine := func() (string, error) {
+					if currentLine >= len(peekLines) {
+						return "", io.EOF
+					}
+					return peekLines[currentLine], nil
+				}
+				consumeLine := func() {
+					currentLine++
+				}
+
+				entries, err := parser.Parse(input, peekLine, consumeLine)
+
+				Expect(err).NotTo(HaveOccurred())
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.LevelFatal))
+				Expect(e.Structured.Stack).To(Equal(
+					"sigs.k8s.io/controller-runtime/pkg/manager.(*controllerManager).Start\n" +
+						"\t/go/pkg/mod/sigs.k8s.io/controller-runtime@v0.19.0/pkg/manager/internal.go:42",
+				))
+			})
+		})
+
 		Context("error cases", func() {
 			It("returns error when metadata is invalid JSON", func() {
 				input := "2025-01-30T15:52:37+09:00\tINFO\tsetup\tstarting manager\t{\"invalid\": json}"
@@ -249,6 +351,14 @@ other.example.com/path/to/file.(*Struct).Method
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("failed to unmarshal metadata"))
 			})
+
+			It("returns error for a bare JSON object", func() {
+				input := `{"level":"info","ts":1738219957.123,"msg":"starting manager"}`
+				_, err := parser.Parse(input, nil, nil)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid log format"))
+			})
 		})
 	})
 })
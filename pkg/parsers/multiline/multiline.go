@@ -0,0 +1,196 @@
+package multiline
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// errNotMultiline is returned for lines that do not start a known multi-line block
+var errNotMultiline = errors.New("not the start of a multi-line block")
+
+const pythonTraceback = "Traceback (most recent call last):"
+
+var (
+	// goPanicRegex matches the first line of a Go panic or a fatal runtime error
+	goPanicRegex = regexp.MustCompile(`^(panic|fatal error): `)
+	// goFuncRegex matches the function lines of a goroutine dump, e.g. main.main() or pkg.(*T).M(0x1, ...)
+	goFuncRegex = regexp.MustCompile(`^\S+\(.*\)$`)
+
+	// javaExceptionRegex matches the first line of a Java exception, e.g.
+	// Exception in thread "main" java.lang.IllegalStateException: message
+	javaExceptionRegex = regexp.MustCompile(`^(?:Exception in thread "[^"]*" )?(?:[a-zA-Z_$][\w$]*\.)+[\w$]*(?:Exception|Error|Throwable)\b(?::.*)?$`)
+	javaFrameRegex     = regexp.MustCompile(`^\s+at \S`)
+	javaMoreRegex      = regexp.MustCompile(`^\s+\.\.\. \d+ (?:more|common frames omitted)$`)
+	javaCauseRegex     = regexp.MustCompile(`^\s*(?:Caused by|Suppressed): `)
+
+	// yamlKeyRegex matches a mapping key, e.g. kind: Pod or metadata:
+	yamlKeyRegex = regexp.MustCompile(`^[\w"][\w.\-/"]*:(?: .*)?$`)
+	// yamlNestedRegex matches an indented mapping key or list item
+	yamlNestedRegex = regexp.MustCompile(`^\s+(?:- |[\w"][\w.\-/"]*:(?: |$))`)
+)
+
+// pythonChainLines separate the tracebacks of chained exceptions
+var pythonChainLines = map[string]bool{
+	"During handling of the above exception, another exception occurred:":  true,
+	"The above exception was the direct cause of the following exception:": true,
+}
+
+var _ receriver.Parser = &Parser{}
+
+// Parser groups blocks of lines that other parsers would turn into an unstructured entry per line:
+// Go panics with their goroutine dumps, Java and Python stack traces, and YAML documents
+// The block becomes a single entry with the first line, or the exception, as its message
+// and the remaining lines as its stack
+type Parser struct {
+}
+
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	switch {
+	case goPanicRegex.MatchString(line):
+		level := entry.LevelPanic
+		if strings.HasPrefix(line, "fatal error: ") {
+			level = entry.LevelFatal
+		}
+		stack := collect(peekLine, consumeLine, isGoPanicLine)
+		return newEntry(level, line, stack), nil
+
+	case line == pythonTraceback:
+		lines := collectPython(line, peekLine, consumeLine)
+		// the exception is the last line, below the frames
+		message := lines[len(lines)-1]
+		return newEntry(entry.LevelError, message, lines[:len(lines)-1]), nil
+
+	case javaExceptionRegex.MatchString(line) && nextMatches(peekLine, javaFrameRegex):
+		stack := collect(peekLine, consumeLine, isJavaLine)
+		return newEntry(entry.LevelError, line, stack), nil
+
+	case isYAMLStart(line, peekLine):
+		lines := append([]string{line}, collect(peekLine, consumeLine, isYAMLLine)...)
+		if line == "---" {
+			// the separator is not worth a message
+			lines = lines[1:]
+		}
+		return newEntry(entry.LevelInfo, lines[0], lines[1:]), nil
+	}
+	return nil, errNotMultiline
+}
+
+func newEntry(level entry.Level, message string, stack []string) []*entry.Entry {
+	return []*entry.Entry{{
+		Structured: &entry.Structured{
+			Timestamp: time.Now(),
+			ReadTime:  true, // the blocks have no time of their own
+			Level:     level,
+			Message:   message,
+			Stack:     strings.Trim(strings.Join(stack, "\n"), "\n"),
+		},
+	}}
+}
+
+// collect consumes the following lines as long as they belong to the block
+func collect(peekLine func() (string, error), consumeLine func(), belongs func(string) bool) []string {
+	var lines []string
+	for {
+		next, err := peekLine()
+		if err != nil || !belongs(next) {
+			return lines
+		}
+		lines = append(lines, next)
+		consumeLine()
+	}
+}
+
+// nextMatches reports whether the next line matches re, without consuming it
+func nextMatches(peekLine func() (string, error), re *regexp.Regexp) bool {
+	next, err := peekLine()
+	return err == nil && re.MatchString(next)
+}
+
+// isGoPanicLine reports whether a line belongs to the goroutine dump of a panic
+func isGoPanicLine(line string) bool {
+	return line == "" ||
+		strings.HasPrefix(line, "\t") ||
+		strings.HasPrefix(line, "goroutine ") ||
+		strings.HasPrefix(line, "created by ") ||
+		strings.HasPrefix(line, "[signal ") ||
+		strings.HasPrefix(line, "panic: ") || // panics while panicking
+		strings.HasPrefix(line, "exit status ") || // printed by go run
+		line == "...additional frames elided..." ||
+		goFuncRegex.MatchString(line)
+}
+
+func isJavaLine(line string) bool {
+	return javaFrameRegex.MatchString(line) || javaMoreRegex.MatchString(line) || javaCauseRegex.MatchString(line)
+}
+
+// collectPython consumes a traceback up to its exception line, including chained exceptions
+func collectPython(line string, peekLine func() (string, error), consumeLine func()) []string {
+	lines := []string{line}
+	inFrames := true // whether the exception line of the current traceback is still to come
+loop:
+	for {
+		next, err := peekLine()
+		if err != nil {
+			break
+		}
+		switch {
+		case next == pythonTraceback:
+			inFrames = true
+		case strings.HasPrefix(next, " "):
+			if !inFrames {
+				break loop
+			}
+		case next == "" || pythonChainLines[next]:
+			if inFrames {
+				break loop
+			}
+		case inFrames:
+			// the exception, e.g. ValueError: invalid literal
+			inFrames = false
+		default:
+			break loop
+		}
+		lines = append(lines, next)
+		consumeLine()
+	}
+	// a blank line after the last exception is consumed while looking for a chained one
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isYAMLStart reports whether a line starts a YAML document: a --- separator,
+// an apiVersion of a Kubernetes object, or a key followed by an indented block
+func isYAMLStart(line string, peekLine func() (string, error)) bool {
+	switch {
+	case line == "---":
+		return nextMatches(peekLine, yamlKeyRegex)
+	case strings.HasPrefix(line, "apiVersion: ") && yamlKeyRegex.MatchString(line):
+		return true
+	case strings.HasSuffix(line, ":") && yamlKeyRegex.MatchString(line):
+		return nextMatches(peekLine, yamlNestedRegex)
+	}
+	return false
+}
+
+// isYAMLLine reports whether a line continues a YAML document
+// Lines starting another kind of block end the document
+func isYAMLLine(line string) bool {
+	if goPanicRegex.MatchString(line) || line == pythonTraceback {
+		return false
+	}
+	return line == "---" ||
+		strings.HasPrefix(line, "- ") ||
+		(strings.HasPrefix(line, " ") && strings.TrimSpace(line) != "") ||
+		yamlKeyRegex.MatchString(line)
+}
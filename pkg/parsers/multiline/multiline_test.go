@@ -0,0 +1,207 @@
+package multiline_test
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/parsers/multiline"
+)
+
+var _ = Describe("Multiline", func() {
+	var parser *multiline.Parser
+
+	BeforeEach(func() {
+		parser = multiline.NewParser()
+	})
+
+	// parse parses the first line of stream, returning the entries and the lines left unconsumed
+	parse := func(stream string) ([]*entry.Entry, []string, error) {
+		lines := strings.Split(stream, "\n")
+		next := 1
+		peekLine := func() (string, error) {
+			if next >= len(lines) {
+				return "", io.EOF
+			}
+			return lines[next], nil
+		}
+		consumeLine := func() {
+			next++
+		}
+		entries, err := parser.Parse(lines[0], peekLine, consumeLine)
+		return entries, lines[next:], err
+	}
+
+	DescribeTable("grouping blocks",
+		func(stream string, level entry.Level, message, stack string, rest []string) {
+			entries, remaining, err := parse(stream)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Structured.Level).To(Equal(level))
+			Expect(entries[0].Structured.Message).To(Equal(message))
+			Expect(entries[0].Structured.Stack).To(Equal(stack))
+			Expect(entries[0].Structured.ReadTime).To(BeTrue())
+			Expect(entries[0].Structured.Timestamp).To(BeTemporally("~", time.Now(), time.Second))
+			Expect(remaining).To(Equal(rest))
+		},
+		Entry("Go panic",
+			"panic: runtime error: index out of range [3] with length 3\n"+
+				"\n"+
+				"goroutine 1 [running]:\n"+
+				"main.lookup(...)\n"+
+				"\t/app/main.go:12\n"+
+				"main.main()\n"+
+				"\t/app/main.go:8 +0x1d\n"+
+				"exit status 2\n"+
+				"2025-01-30T15:52:37Z\tINFO\tnext",
+			entry.LevelPanic,
+			"panic: runtime error: index out of range [3] with length 3",
+			"goroutine 1 [running]:\nmain.lookup(...)\n\t/app/main.go:12\nmain.main()\n\t/app/main.go:8 +0x1d\nexit status 2",
+			[]string{"2025-01-30T15:52:37Z\tINFO\tnext"},
+		),
+		Entry("recovered Go panic with several goroutines",
+			"panic: boom [recovered]\n"+
+				"\tpanic: boom\n"+
+				"\n"+
+				"goroutine 7 [running]:\n"+
+				"sigs.k8s.io/controller-runtime/pkg/internal/controller.(*Controller).Reconcile.func1()\n"+
+				"\t/go/pkg/mod/sigs.k8s.io/controller-runtime/pkg/internal/controller/controller.go:111 +0x1e5\n"+
+				"created by sigs.k8s.io/controller-runtime/pkg/internal/controller.(*Controller).Start in goroutine 1\n"+
+				"\t/go/pkg/mod/sigs.k8s.io/controller-runtime/pkg/internal/controller/controller.go:220 +0x4d\n"+
+				"\n"+
+				"goroutine 1 [chan receive]:\n"+
+				"main.main()\n"+
+				"\t/app/main.go:20 +0x3c",
+			entry.LevelPanic,
+			"panic: boom [recovered]",
+			"\tpanic: boom\n\ngoroutine 7 [running]:\n"+
+				"sigs.k8s.io/controller-runtime/pkg/internal/controller.(*Controller).Reconcile.func1()\n"+
+				"\t/go/pkg/mod/sigs.k8s.io/controller-runtime/pkg/internal/controller/controller.go:111 +0x1e5\n"+
+				"created by sigs.k8s.io/controller-runtime/pkg/internal/controller.(*Controller).Start in goroutine 1\n"+
+				"\t/go/pkg/mod/sigs.k8s.io/controller-runtime/pkg/internal/controller/controller.go:220 +0x4d\n"+
+				"\ngoroutine 1 [chan receive]:\nmain.main()\n\t/app/main.go:20 +0x3c",
+			[]string{},
+		),
+		Entry("Go fatal error",
+			"fatal error: concurrent map writes\n"+
+				"\n"+
+				"goroutine 12 [running]:\n"+
+				"main.worker()\n"+
+				"\t/app/main.go:30 +0x45\n"+
+				"Starting server",
+			entry.LevelFatal,
+			"fatal error: concurrent map writes",
+			"goroutine 12 [running]:\nmain.worker()\n\t/app/main.go:30 +0x45",
+			[]string{"Starting server"},
+		),
+		Entry("Java exception with causes",
+			"Exception in thread \"main\" java.lang.IllegalStateException: cannot connect\n"+
+				"\tat com.example.Client.connect(Client.java:42)\n"+
+				"\tat com.example.Main.main(Main.java:10)\n"+
+				"Caused by: java.net.ConnectException: Connection refused\n"+
+				"\tat java.base/sun.nio.ch.Net.connect0(Native Method)\n"+
+				"\t... 2 more\n"+
+				"Shutting down",
+			entry.LevelError,
+			"Exception in thread \"main\" java.lang.IllegalStateException: cannot connect",
+			"\tat com.example.Client.connect(Client.java:42)\n"+
+				"\tat com.example.Main.main(Main.java:10)\n"+
+				"Caused by: java.net.ConnectException: Connection refused\n"+
+				"\tat java.base/sun.nio.ch.Net.connect0(Native Method)\n"+
+				"\t... 2 more",
+			[]string{"Shutting down"},
+		),
+		Entry("Python traceback",
+			"Traceback (most recent call last):\n"+
+				"  File \"/app/main.py\", line 3, in <module>\n"+
+				"    int(\"x\")\n"+
+				"ValueError: invalid literal for int() with base 10: 'x'\n"+
+				"INFO:root:done",
+			entry.LevelError,
+			"ValueError: invalid literal for int() with base 10: 'x'",
+			"Traceback (most recent call last):\n  File \"/app/main.py\", line 3, in <module>\n    int(\"x\")",
+			[]string{"INFO:root:done"},
+		),
+		Entry("chained Python exceptions",
+			"Traceback (most recent call last):\n"+
+				"  File \"/app/main.py\", line 2, in load\n"+
+				"    open(path)\n"+
+				"FileNotFoundError: config.yaml\n"+
+				"\n"+
+				"During handling of the above exception, another exception occurred:\n"+
+				"\n"+
+				"Traceback (most recent call last):\n"+
+				"  File \"/app/main.py\", line 4, in load\n"+
+				"    raise RuntimeError(\"no config\")\n"+
+				"RuntimeError: no config\n"+
+				"\n"+
+				"next",
+			entry.LevelError,
+			"RuntimeError: no config",
+			"Traceback (most recent call last):\n"+
+				"  File \"/app/main.py\", line 2, in load\n"+
+				"    open(path)\n"+
+				"FileNotFoundError: config.yaml\n"+
+				"\n"+
+				"During handling of the above exception, another exception occurred:\n"+
+				"\n"+
+				"Traceback (most recent call last):\n"+
+				"  File \"/app/main.py\", line 4, in load\n"+
+				"    raise RuntimeError(\"no config\")",
+			[]string{"next"},
+		),
+		Entry("Kubernetes object as YAML",
+			"apiVersion: v1\n"+
+				"kind: ConfigMap\n"+
+				"metadata:\n"+
+				"  name: settings\n"+
+				"data:\n"+
+				"  mode: fast\n"+
+				"\n"+
+				"done",
+			entry.LevelInfo,
+			"apiVersion: v1",
+			"kind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: fast",
+			[]string{"", "done"},
+		),
+		Entry("YAML document after a separator",
+			"---\n"+
+				"spec:\n"+
+				"  replicas: 3\n"+
+				"  ports:\n"+
+				"  - 80\n"+
+				"done",
+			entry.LevelInfo,
+			"spec:",
+			"  replicas: 3\n  ports:\n  - 80",
+			[]string{"done"},
+		),
+		Entry("YAML block under a key",
+			"status:\n"+
+				"  phase: Running\n"+
+				"  conditions:\n"+
+				"  - type: Ready\n"+
+				"panic: oops",
+			entry.LevelInfo,
+			"status:",
+			"  phase: Running\n  conditions:\n  - type: Ready",
+			[]string{"panic: oops"},
+		),
+	)
+
+	DescribeTable("leaving other lines to other parsers",
+		func(stream string) {
+			_, remaining, err := parse(stream)
+			Expect(err).To(HaveOccurred())
+			Expect(remaining).To(HaveLen(len(strings.Split(stream, "\n")) - 1))
+		},
+		Entry("plain line", "Starting manager\nnext"),
+		Entry("exception name without frames", "java.lang.IllegalStateException: oops\nnext"),
+		Entry("key without an indented block", "Status:\nready"),
+		Entry("separator without a document", "---\nnext line"),
+	)
+})
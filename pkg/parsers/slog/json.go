@@ -0,0 +1,73 @@
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+var _ receriver.Parser = &JSONParser{}
+
+// JSONParser parses lines written by slog.JSONHandler, e.g.
+// {"time":"2025-01-30T15:52:37.123+09:00","level":"INFO","msg":"starting manager","req":{"method":"GET"}}
+// Groups are already nested objects and are kept as nested maps
+type JSONParser struct {
+}
+
+func NewJSONParser() *JSONParser {
+	return &JSONParser{}
+}
+
+func (p *JSONParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	if !strings.HasPrefix(line, "{") {
+		return nil, fmt.Errorf("invalid log format: not a JSON object")
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal log: %w", err)
+	}
+
+	rawLevel, ok := data[levelKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing %q", levelKey)
+	}
+	message, ok := data[messageKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing %q", messageKey)
+	}
+	levelValue, err := ParseLevelValue(rawLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse level: %w", err)
+	}
+	// the time attribute is omitted when the record has no time
+	timestamp, readTime := time.Now(), true
+	if rawTime, ok := data[timeKey]; ok {
+		s, ok := rawTime.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid log format: %q is not a string", timeKey)
+		}
+		if timestamp, err = parseTime(s); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+		readTime = false
+	}
+
+	delete(data, timeKey)
+	delete(data, levelKey)
+	delete(data, messageKey)
+
+	return []*entry.Entry{{
+		Structured: &entry.Structured{
+			Timestamp: timestamp,
+			ReadTime:  readTime,
+			Level:     LevelForValue(levelValue),
+			Verbosity: Verbosity(levelValue),
+			Message:   message,
+			Data:      data,
+		},
+	}}, nil
+}
@@ -0,0 +1,120 @@
+package slog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+// Keys written by log/slog's built-in handlers
+const (
+	timeKey    = "time"
+	levelKey   = "level"
+	messageKey = "msg"
+)
+
+// Numeric values of the built-in slog levels
+const (
+	levelDebug = -4
+	levelInfo  = 0
+	levelWarn  = 4
+	levelError = 8
+)
+
+// levelRegex matches slog level strings such as INFO, DEBUG-4 or ERROR+2
+var levelRegex = regexp.MustCompile(`^(DEBUG|INFO|WARN|ERROR)([+-]\d+)?$`)
+
+// ParseLevel converts slog level string to entry.Level
+// Levels between the built-in ones are rounded down, e.g. INFO+2 is info and WARN-1 is info,
+// and levels below DEBUG are trace
+func ParseLevel(level string) (entry.Level, error) {
+	n, err := ParseLevelValue(level)
+	if err != nil {
+		return "", err
+	}
+	return LevelForValue(n), nil
+}
+
+// LevelForValue converts a numeric slog level to entry.Level
+func LevelForValue(n int) entry.Level {
+	switch {
+	case n < levelDebug:
+		return entry.LevelTrace
+	case n < levelInfo:
+		return entry.LevelDebug
+	case n < levelWarn:
+		return entry.LevelInfo
+	case n < levelError:
+		return entry.LevelWarning
+	default:
+		return entry.LevelError
+	}
+}
+
+// ParseLevelValue converts slog level string to its numeric value, e.g. DEBUG-4 is -8
+func ParseLevelValue(level string) (int, error) {
+	m := levelRegex.FindStringSubmatch(level)
+	if m == nil {
+		return 0, fmt.Errorf("unknown level: %s", level)
+	}
+	var n int
+	switch m[1] {
+	case "DEBUG":
+		n = levelDebug
+	case "INFO":
+		n = levelInfo
+	case "WARN":
+		n = levelWarn
+	case "ERROR":
+		n = levelError
+	}
+	if m[2] != "" {
+		offset, err := strconv.Atoi(m[2])
+		if err != nil {
+			return 0, fmt.Errorf("unknown level: %s", level)
+		}
+		n += offset
+	}
+	return n, nil
+}
+
+// Verbosity converts a numeric slog level below INFO to the equivalent logr V-level,
+// following the logr/slog bridge which maps V(n) to slog.Level(-n)
+func Verbosity(n int) int {
+	if n >= levelInfo {
+		return 0
+	}
+	return -n
+}
+
+// parseTime parses the time attribute written by slog handlers (RFC3339 with milliseconds)
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// setNested stores value in data under a dotted key path, e.g. a.b.c, creating intermediate maps
+// If a path element is already used by a non-group value, the dotted key is stored as is
+func setNested(data map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := data
+	for i, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists {
+			group := make(map[string]interface{})
+			current[part] = group
+			current = group
+			continue
+		}
+		group, ok := next.(map[string]interface{})
+		if !ok {
+			current[strings.Join(parts[i:], ".")] = value
+			return
+		}
+		current = group
+	}
+	current[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,164 @@
+package slog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	slogparser "github.com/appthrust/kutelog/pkg/parsers/slog"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// logLine writes a single record with the given handler and returns the written line
+func logLine(newHandler func(*bytes.Buffer) slog.Handler, log func(*slog.Logger)) string {
+	var buf bytes.Buffer
+	log(slog.New(newHandler(&buf)))
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func textHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.Level(-8)})
+}
+
+func jsonHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.Level(-8)})
+}
+
+var _ = Describe("Slog", func() {
+	Describe("ParseLevel", func() {
+		DescribeTable("parsing levels",
+			func(input string, expected entry.Level, expectedValue int) {
+				level, err := slogparser.ParseLevel(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(level).To(Equal(expected))
+
+				value, err := slogparser.ParseLevelValue(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(value).To(Equal(expectedValue))
+			},
+			Entry("DEBUG-4", "DEBUG-4", entry.LevelTrace, -8),
+			Entry("DEBUG-1", "DEBUG-1", entry.LevelTrace, -5),
+			Entry("DEBUG", "DEBUG", entry.LevelDebug, -4),
+			Entry("INFO", "INFO", entry.LevelInfo, 0),
+			Entry("INFO+2", "INFO+2", entry.LevelInfo, 2),
+			Entry("WARN-1", "WARN-1", entry.LevelInfo, 3),
+			Entry("WARN", "WARN", entry.LevelWarning, 4),
+			Entry("ERROR", "ERROR", entry.LevelError, 8),
+			Entry("ERROR+4", "ERROR+4", entry.LevelError, 12),
+		)
+
+		DescribeTable("rejecting unknown levels",
+			func(input string) {
+				_, err := slogparser.ParseLevel(input)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unknown level"))
+			},
+			Entry("lowercase", "info"),
+			Entry("unknown name", "TRACE"),
+			Entry("invalid offset", "INFO+x"),
+		)
+	})
+
+	for _, format := range []struct {
+		name       string
+		newHandler func(*bytes.Buffer) slog.Handler
+		newParser  func() receriver.Parser
+	}{
+		{"TextParser", textHandler, func() receriver.Parser { return slogparser.NewTextParser() }},
+		{"JSONParser", jsonHandler, func() receriver.Parser { return slogparser.NewJSONParser() }},
+	} {
+		Describe(format.name, func() {
+			var parser receriver.Parser
+
+			BeforeEach(func() {
+				parser = format.newParser()
+			})
+
+			It("parses a record written by the handler", func() {
+				line := logLine(format.newHandler, func(l *slog.Logger) {
+					l.Warn("cache miss", "controller", "machine", "attempt", 3)
+				})
+
+				entries, err := parser.Parse(line, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+
+				e := entries[0]
+				Expect(e.Structured.Timestamp.IsZero()).To(BeFalse())
+				Expect(e.Structured.Level).To(Equal(entry.LevelWarning))
+				Expect(e.Structured.Message).To(Equal("cache miss"))
+				Expect(e.Structured.Data).To(HaveKeyWithValue("controller", "machine"))
+				Expect(e.Structured.Data).To(HaveKey("attempt"))
+			})
+
+			It("reconstructs grouped attributes into nested maps", func() {
+				line := logLine(format.newHandler, func(l *slog.Logger) {
+					l.WithGroup("req").Info("handled", slog.Group("user", "name", "alice"), "method", "GET")
+				})
+
+				entries, err := parser.Parse(line, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries[0].Structured.Data).To(Equal(map[string]interface{}{
+					"req": map[string]interface{}{
+						"method": "GET",
+						"user": map[string]interface{}{
+							"name": "alice",
+						},
+					},
+				}))
+			})
+
+			It("maps custom numeric levels", func() {
+				line := logLine(format.newHandler, func(l *slog.Logger) {
+					l.Log(context.Background(), slog.Level(-8), "very verbose")
+				})
+
+				entries, err := parser.Parse(line, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries[0].Structured.Level).To(Equal(entry.LevelTrace))
+				Expect(entries[0].Structured.Verbosity).To(Equal(8))
+				Expect(entries[0].Structured.Message).To(Equal("very verbose"))
+			})
+
+			It("rejects lines that are not slog records", func() {
+				_, err := parser.Parse("starting manager", nil, nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	}
+
+	Describe("TextParser", func() {
+		It("keeps dotted keys that conflict with plain attributes", func() {
+			parser := slogparser.NewTextParser()
+			entries, err := parser.Parse(`level=INFO msg=hello a=1 a.b=2`, nil, nil)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].Structured.Data).To(Equal(map[string]interface{}{
+				"a":   "1",
+				"a.b": "2",
+			}))
+		})
+
+		It("rejects logfmt lines with non-slog levels", func() {
+			parser := slogparser.NewTextParser()
+			_, err := parser.Parse(`level=info msg=hello`, nil, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("JSONParser", func() {
+		It("rejects zap lines", func() {
+			parser := slogparser.NewJSONParser()
+			_, err := parser.Parse(`{"level":"info","ts":1738219957.123,"msg":"hello"}`, nil, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package slog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/parsers/logfmt"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+var _ receriver.Parser = &TextParser{}
+
+// TextParser parses lines written by slog.TextHandler, e.g.
+// time=2025-01-30T15:52:37.123+09:00 level=INFO msg="starting manager" req.method=GET
+// Grouped attributes (req.method) are reconstructed into nested maps
+type TextParser struct {
+}
+
+func NewTextParser() *TextParser {
+	return &TextParser{}
+}
+
+func (p *TextParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	pairs, err := logfmt.Decode(line)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode text: %w", err)
+	}
+
+	var rawTime, rawLevel, message string
+	var hasLevel, hasMessage bool
+	data := make(map[string]interface{})
+	for _, pair := range pairs {
+		switch pair.Key {
+		case timeKey:
+			rawTime = pair.Value
+		case levelKey:
+			rawLevel, hasLevel = pair.Value, true
+		case messageKey:
+			message, hasMessage = pair.Value, true
+		default:
+			setNested(data, pair.Key, pair.Value)
+		}
+	}
+	if !hasLevel {
+		return nil, fmt.Errorf("invalid log format: missing %q", levelKey)
+	}
+	if !hasMessage {
+		return nil, fmt.Errorf("invalid log format: missing %q", messageKey)
+	}
+
+	levelValue, err := ParseLevelValue(rawLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse level: %w", err)
+	}
+	// the time attribute is omitted when the record has no time
+	timestamp, readTime := time.Now(), true
+	if rawTime != "" {
+		if timestamp, err = parseTime(rawTime); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+		readTime = false
+	}
+
+	return []*entry.Entry{{
+		Structured: &entry.Structured{
+			Timestamp: timestamp,
+			ReadTime:  readTime,
+			Level:     LevelForValue(levelValue),
+			Verbosity: Verbosity(levelValue),
+			Message:   message,
+			Data:      data,
+		},
+	}}, nil
+}
@@ -0,0 +1,139 @@
+package zap
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// Keys used by zap's production JSON encoder (as configured by controller-runtime)
+const (
+	timestampKey  = "ts"
+	levelKey      = "level"
+	messageKey    = "msg"
+	stacktraceKey = "stacktrace"
+)
+
+// verbosityRegex matches levels below debug, which zap encodes as level(-n)
+var verbosityRegex = regexp.MustCompile(`^(?i:level)\(-(\d+)\)$`)
+
+// iso8601Layout is the layout of zapcore.ISO8601TimeEncoder
+const iso8601Layout = "2006-01-02T15:04:05.000Z0700"
+
+var _ receriver.Parser = &Parser{}
+
+// Parser parses JSON lines written by zap's production encoder, e.g.
+// {"level":"info","ts":1738219957.123,"logger":"setup","msg":"starting manager"}
+type Parser struct {
+}
+
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+func (p *Parser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	if !strings.HasPrefix(line, "{") {
+		return nil, fmt.Errorf("invalid log format: not a JSON object")
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal log: %w", err)
+	}
+
+	rawLevel, ok := data[levelKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing %q", levelKey)
+	}
+	message, ok := data[messageKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing %q", messageKey)
+	}
+	rawTimestamp, ok := data[timestampKey]
+	if !ok {
+		return nil, fmt.Errorf("invalid log format: missing %q", timestampKey)
+	}
+
+	level, err := ParseLevel(rawLevel)
+	if err != nil {
+		// keep the line structured, using the level as written
+		level = entry.Level(strings.ToLower(rawLevel))
+	}
+	timestamp, err := ParseTimestamp(rawTimestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	stack, _ := data[stacktraceKey].(string)
+
+	// everything else, including "logger" and "caller", is kept as data
+	delete(data, timestampKey)
+	delete(data, levelKey)
+	delete(data, messageKey)
+	delete(data, stacktraceKey)
+
+	return []*entry.Entry{{
+		Structured: &entry.Structured{
+			Timestamp: timestamp,
+			Level:     level,
+			Verbosity: ParseVerbosity(rawLevel),
+			Message:   message,
+			Data:      data,
+			Stack:     stack,
+		},
+	}}, nil
+}
+
+// ParseLevel converts zap level string to entry.Level
+// Levels below debug, which zap prints as level(-n) for logr V(n), are reported as debug
+func ParseLevel(level string) (entry.Level, error) {
+	if ParseVerbosity(level) > 0 {
+		return entry.LevelDebug, nil
+	}
+	return entry.ParseLevel(level)
+}
+
+// ParseVerbosity extracts the logr V-level from a zap level string such as level(-2)
+// It returns 0 for levels without verbosity
+func ParseVerbosity(level string) int {
+	m := verbosityRegex.FindStringSubmatch(level)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ParseTimestamp converts a zap timestamp to time.Time
+// Numeric timestamps may be epoch seconds (the default), milliseconds or nanoseconds;
+// string timestamps may be RFC3339 or ISO8601
+func ParseTimestamp(ts interface{}) (time.Time, error) {
+	switch v := ts.(type) {
+	case float64:
+		switch {
+		case v < 1e11: // seconds; float64 only carries microsecond precision at this scale
+			return time.UnixMicro(int64(math.Round(v * 1e6))), nil
+		case v < 1e14: // milliseconds
+			return time.UnixMilli(int64(v)), nil
+		case v < 1e17: // microseconds
+			return time.UnixMicro(int64(v)), nil
+		default: // nanoseconds
+			return time.Unix(0, int64(v)), nil
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		return time.Parse(iso8601Layout, v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp type: %T", ts)
+	}
+}
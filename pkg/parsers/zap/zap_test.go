@@ -0,0 +1,152 @@
+package zap_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/parsers/zap"
+)
+
+var _ = Describe("Zap", func() {
+	Describe("ParseLevel", func() {
+		DescribeTable("parsing log levels",
+			func(input string, expected entry.Level, expectError bool) {
+				level, err := zap.ParseLevel(input)
+
+				if expectError {
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("unknown level"))
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(level).To(Equal(expected))
+				}
+			},
+			Entry("debug level", "debug", entry.LevelDebug, false),
+			Entry("info level", "info", entry.LevelInfo, false),
+			Entry("warn level", "warn", entry.LevelWarning, false),
+			Entry("error level", "error", entry.LevelError, false),
+			Entry("dpanic level", "dpanic", entry.LevelPanic, false),
+			Entry("panic level", "panic", entry.LevelPanic, false),
+			Entry("fatal level", "fatal", entry.LevelFatal, false),
+			Entry("V-level", "level(-2)", entry.LevelDebug, false),
+			Entry("capital level", "INFO", entry.LevelInfo, false),
+			Entry("unknown level", "verbose", entry.Level(""), true),
+		)
+	})
+
+	Describe("ParseTimestamp", func() {
+		expected := time.Date(2025, 1, 30, 6, 52, 37, 123000000, time.UTC)
+
+		DescribeTable("parsing timestamps",
+			func(input interface{}) {
+				timestamp, err := zap.ParseTimestamp(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(timestamp).To(BeTemporally("~", expected, time.Microsecond))
+			},
+			Entry("epoch seconds", 1738219957.123),
+			Entry("epoch milliseconds", float64(1738219957123)),
+			Entry("epoch nanoseconds", float64(1738219957123000000)),
+			Entry("RFC3339", "2025-01-30T06:52:37.123Z"),
+			Entry("ISO8601", "2025-01-30T15:52:37.123+0900"),
+		)
+
+		It("rejects unsupported types", func() {
+			_, err := zap.ParseTimestamp(true)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Parser", func() {
+		var parser *zap.Parser
+
+		BeforeEach(func() {
+			parser = zap.NewParser()
+		})
+
+		Context("normal log line", func() {
+			It("parses successfully", func() {
+				input := `{"level":"info","ts":1738219957.123,"logger":"setup","caller":"cmd/main.go:42","msg":"starting manager","name":"test"}`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+
+				e := entries[0]
+				Expect(e.Structured.Timestamp.Unix()).To(Equal(int64(1738219957)))
+				Expect(e.Structured.Level).To(Equal(entry.LevelInfo))
+				Expect(e.Structured.Message).To(Equal("starting manager"))
+				Expect(e.Structured.Data).To(Equal(map[string]interface{}{
+					"logger": "setup",
+					"caller": "cmd/main.go:42",
+					"name":   "test",
+				}))
+				Expect(e.Structured.Stack).To(BeEmpty())
+			})
+		})
+
+		Context("error log with stack trace", func() {
+			It("moves stacktrace to the stack field", func() {
+				input := `{"level":"error","ts":"2025-01-30T15:53:07.000+0900","logger":"controller-runtime.source.EventHandler","msg":"failed to get informer from cache","error":"test error","stacktrace":"sigs.k8s.io/controller-runtime/pkg/internal/source.(*Kind[...]).Start.func1.1\n\t/go/pkg/mod/sigs.k8s.io/controller-runtime@v0.19.0/pkg/internal/source/kind.go:76"}`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+
+				e := entries[0]
+				Expect(e.Structured.Level).To(Equal(entry.LevelError))
+				Expect(e.Structured.Message).To(Equal("failed to get informer from cache"))
+				Expect(e.Structured.Data).To(Equal(map[string]interface{}{
+					"logger": "controller-runtime.source.EventHandler",
+					"error":  "test error",
+				}))
+				Expect(e.Structured.Stack).To(Equal(
+					"sigs.k8s.io/controller-runtime/pkg/internal/source.(*Kind[...]).Start.func1.1\n" +
+						"\t/go/pkg/mod/sigs.k8s.io/controller-runtime@v0.19.0/pkg/internal/source/kind.go:76",
+				))
+			})
+		})
+
+		Context("V-level log line", func() {
+			It("preserves the verbosity", func() {
+				input := `{"level":"Level(-3)","ts":1738219957.123,"msg":"polling"}`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries[0].Structured.Level).To(Equal(entry.LevelDebug))
+				Expect(entries[0].Structured.Verbosity).To(Equal(3))
+			})
+		})
+
+		Context("unknown level", func() {
+			It("keeps the line structured with the level as written", func() {
+				input := `{"level":"NOTICE","ts":1738219957.123,"msg":"hello"}`
+
+				entries, err := parser.Parse(input, nil, nil)
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries[0].Structured.Level).To(Equal(entry.Level("notice")))
+				Expect(entries[0].Structured.Message).To(Equal("hello"))
+			})
+		})
+
+		Context("error cases", func() {
+			DescribeTable("rejects lines that are not zap JSON",
+				func(input string) {
+					_, err := parser.Parse(input, nil, nil)
+					Expect(err).To(HaveOccurred())
+				},
+				Entry("plain text", "starting manager"),
+				Entry("invalid JSON", `{"level": info}`),
+				Entry("missing level", `{"ts":1738219957.123,"msg":"hello"}`),
+				Entry("missing message", `{"level":"info","ts":1738219957.123}`),
+				Entry("missing timestamp", `{"level":"info","msg":"hello"}`),
+			)
+		})
+	})
+})
@@ -0,0 +1,205 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+type node interface {
+	eval(e *entry.Entry) bool
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(e *entry.Entry) bool { return n.left.eval(e) || n.right.eval(e) }
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(e *entry.Entry) bool { return n.left.eval(e) && n.right.eval(e) }
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(e *entry.Entry) bool { return !n.operand.eval(e) }
+
+// existsNode is a field on its own, true if the field is present and not empty
+type existsNode struct{ field field }
+
+func (n existsNode) eval(e *entry.Entry) bool {
+	value, ok := n.field.get(e)
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+// compareNode compares a field with a literal
+type compareNode struct {
+	field    field
+	op       string
+	text     string
+	number   float64 // the literal as a number, or the severity of a level
+	isNumber bool
+	regexp   *regexp.Regexp
+}
+
+func (n compareNode) eval(e *entry.Entry) bool {
+	value, ok := n.field.get(e)
+	if !ok {
+		return n.op == "!=" || n.op == "!~"
+	}
+	if n.regexp != nil {
+		return n.regexp.MatchString(toString(value)) == (n.op == "=~")
+	}
+	if level, ok := value.(entry.Level); ok {
+		if n.op == "==" || n.op == "!=" {
+			// compare names so that unknown levels, which rank as info, are not equal to info
+			return strings.EqualFold(string(level), n.text) == (n.op == "==")
+		}
+		return compare(n.op, float64(level.Severity())-n.number)
+	}
+	if n.isNumber {
+		if number, ok := toNumber(value); ok {
+			return compare(n.op, number-n.number)
+		}
+	}
+	return compare(n.op, float64(strings.Compare(toString(value), n.text)))
+}
+
+// compare applies op to the sign of the difference of two values
+func compare(op string, diff float64) bool {
+	switch op {
+	case "==":
+		return diff == 0
+	case "!=":
+		return diff != 0
+	case "<":
+		return diff < 0
+	case "<=":
+		return diff <= 0
+	case ">":
+		return diff > 0
+	case ">=":
+		return diff >= 0
+	}
+	return false
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		// values of text formats such as logfmt are strings
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case entry.Level:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// field is a part of an entry referenced by an expression
+type field struct {
+	kind string // level, verbosity, msg, stack, data or source
+	key  string // data key or source field
+}
+
+// get returns the value of the field and whether the entry has it
+func (f field) get(e *entry.Entry) (interface{}, bool) {
+	switch f.kind {
+	case "level":
+		if e.Structured == nil {
+			return entry.LevelInfo, true
+		}
+		return e.Structured.Level, true
+	case "verbosity":
+		if e.Structured == nil {
+			return 0, true
+		}
+		return e.Structured.Verbosity, true
+	case "msg":
+		if e.Structured == nil {
+			return e.Unstructured, true
+		}
+		return e.Structured.Message, true
+	case "stack":
+		if e.Structured == nil || e.Structured.Stack == "" {
+			return nil, false
+		}
+		return e.Structured.Stack, true
+	case "data":
+		if e.Structured == nil {
+			return nil, false
+		}
+		return Lookup(e.Structured.Data, f.key)
+	case "source":
+		value := sourceField(e.Source, f.key)
+		return value, value != ""
+	}
+	return nil, false
+}
+
+// Lookup finds a data value by key, or by dotted path into nested objects
+func Lookup(data map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := data[key]; ok {
+		return value, true
+	}
+	head, rest, found := strings.Cut(key, ".")
+	for found {
+		if nested, ok := data[head].(map[string]interface{}); ok {
+			if value, ok := Lookup(nested, rest); ok {
+				return value, true
+			}
+		}
+		// keys may contain dots themselves, e.g. k8s.io/name
+		var next string
+		next, rest, found = strings.Cut(rest, ".")
+		head += "." + next
+	}
+	return nil, false
+}
+
+func sourceField(source *entry.Source, name string) string {
+	if source == nil {
+		return ""
+	}
+	switch name {
+	case "name":
+		return source.Name
+	case "namespace":
+		return source.Namespace
+	case "pod":
+		return source.Pod
+	case "container":
+		return source.Container
+	case "file":
+		return source.File
+	case "stream":
+		return source.Stream
+	}
+	return ""
+}
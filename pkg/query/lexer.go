@@ -0,0 +1,132 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+// token is a lexical token with its byte offset in the expression
+type token struct {
+	kind  tokenKind
+	text  string // identifier, operator, or the unquoted string
+	pos   int
+	value float64 // for tokenNumber
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators are ordered so that longer operators are matched first
+var operators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!"}
+
+// lex splits the expression into tokens, ending with tokenEOF
+func lex(expression string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(expression) {
+		c := expression[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			pos++
+		case c == '"':
+			end, err := scanQuoted(expression, pos)
+			if err != nil {
+				return nil, err
+			}
+			text, err := strconv.Unquote(expression[pos:end])
+			if err != nil {
+				return nil, syntaxError(expression, pos, "invalid string literal")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			pos = end
+		case c == '\'':
+			// single quoted strings are raw, which keeps regular expressions readable
+			end := strings.IndexByte(expression[pos+1:], '\'')
+			if end < 0 {
+				return nil, syntaxError(expression, pos, "unterminated string literal")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: expression[pos+1 : pos+1+end], pos: pos})
+			pos += end + 2
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := pos + 1
+			for end < len(expression) && (isDigit(expression[end]) || expression[end] == '.') {
+				end++
+			}
+			value, err := strconv.ParseFloat(expression[pos:end], 64)
+			if err != nil {
+				return nil, syntaxError(expression, pos, "invalid number "+strconv.Quote(expression[pos:end]))
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expression[pos:end], pos: pos, value: value})
+			pos = end
+		case isIdentStart(c):
+			end := pos + 1
+			for end < len(expression) && isIdentPart(expression[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expression[pos:end], pos: pos})
+			pos = end
+		default:
+			operator := ""
+			for _, op := range operators {
+				if strings.HasPrefix(expression[pos:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, syntaxError(expression, pos, "unexpected character "+strconv.QuoteRune(rune(c)))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: pos})
+			pos += len(operator)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expression)}), nil
+}
+
+// scanQuoted returns the offset just after the double quoted string starting at start
+func scanQuoted(expression string, start int) (int, error) {
+	for i := start + 1; i < len(expression); i++ {
+		switch expression[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, syntaxError(expression, start, "unterminated string literal")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentPart allows dots for field paths and the characters common in data keys, e.g. k8s.io/name or request-id
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.' || c == '-' || c == '/'
+}
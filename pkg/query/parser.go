@@ -0,0 +1,190 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+// parser is a recursive descent parser of
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" or ")" | field [ operator value ]
+type parser struct {
+	expression string
+	tokens     []token
+	pos        int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator
+func (p *parser) accept(operator string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return syntaxError(p.expression, t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected \")\", found %s", closing)
+		}
+		return inner, nil
+	case tokenIdent:
+		f, err := p.parseField(t)
+		if err != nil {
+			return nil, err
+		}
+		op := p.peek()
+		if op.kind != tokenOperator || !isComparison(op.text) {
+			return existsNode{f}, nil
+		}
+		p.next()
+		return p.parseComparison(f, op)
+	default:
+		return nil, p.errorf(t, "expected field, found %s", t)
+	}
+}
+
+func isComparison(operator string) bool {
+	switch operator {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+		return true
+	}
+	return false
+}
+
+// parseField resolves a field name such as level or data.request.method
+func (p *parser) parseField(t token) (field, error) {
+	head, rest, _ := strings.Cut(t.text, ".")
+	switch head {
+	case "level", "verbosity", "msg", "message", "stack":
+		if rest != "" {
+			return field{}, p.errorf(t, "field %s has no subfields", head)
+		}
+		if head == "message" {
+			head = "msg"
+		}
+		return field{kind: head}, nil
+	case "data":
+		if rest == "" {
+			return field{}, p.errorf(t, "expected data.<key>")
+		}
+		return field{kind: head, key: rest}, nil
+	case "source":
+		switch rest {
+		case "name", "namespace", "pod", "container", "file", "stream":
+			return field{kind: head, key: rest}, nil
+		}
+		return field{}, p.errorf(t, "unknown source field %q", rest)
+	}
+	return field{}, p.errorf(t, "unknown field %q", t.text)
+}
+
+func (p *parser) parseComparison(f field, op token) (node, error) {
+	v := p.next()
+	c := compareNode{field: f, op: op.text}
+	switch v.kind {
+	case tokenString, tokenIdent:
+		// bare words are strings, e.g. level >= warning
+		c.text = v.text
+	case tokenNumber:
+		c.text = v.text
+		c.number = v.value
+		c.isNumber = true
+	default:
+		return nil, p.errorf(v, "expected value after %s, found %s", op.text, v)
+	}
+
+	switch {
+	case op.text == "=~" || op.text == "!~":
+		re, err := regexp.Compile(c.text)
+		if err != nil {
+			return nil, p.errorf(v, "invalid regular expression: %v", err)
+		}
+		c.regexp = re
+	case f.kind == "level":
+		level, err := entry.ParseLevel(c.text)
+		if err != nil {
+			if op.text == "==" || op.text == "!=" {
+				// levels kept as written by the parsers, e.g. notice, can only be matched by name
+				break
+			}
+			return nil, p.errorf(v, "%v", err)
+		}
+		c.text = string(level)
+		c.number = float64(level.Severity())
+		c.isNumber = true
+	case f.kind == "verbosity" && !c.isNumber:
+		return nil, p.errorf(v, "expected number, found %s", v)
+	}
+	return c, nil
+}
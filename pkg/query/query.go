@@ -0,0 +1,71 @@
+// Package query implements the filter expressions of kutelog, e.g. level >= warning && msg =~ "reconcil"
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+// Query is a compiled filter expression, safe for concurrent use
+type Query struct {
+	expression string
+	root       node
+}
+
+// SyntaxError reports an invalid expression and where in it the problem is
+type SyntaxError struct {
+	Expression string
+	Offset     int // byte offset in Expression
+	Message    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s\n\t%s\n\t%s^", e.Offset+1, e.Message, e.Expression, strings.Repeat(" ", e.Offset))
+}
+
+func syntaxError(expression string, offset int, message string) *SyntaxError {
+	return &SyntaxError{Expression: expression, Offset: offset, Message: message}
+}
+
+// Compile parses an expression, returning a *SyntaxError if it is invalid
+func Compile(expression string) (*Query, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expression: expression, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, p.errorf(next, "unexpected %s", next)
+	}
+	return &Query{expression: expression, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the expression is invalid
+func MustCompile(expression string) *Query {
+	q, err := Compile(expression)
+	if err != nil {
+		panic(fmt.Sprintf("query: Compile(%q): %v", expression, err))
+	}
+	return q
+}
+
+// Match reports whether the entry satisfies the query; a nil query matches everything
+// Unstructured entries have level info and their text as msg, and a comparison with a
+// missing field is false, except for != and !~ which are true
+func (q *Query) Match(e *entry.Entry) bool {
+	if q == nil {
+		return true
+	}
+	return q.root.eval(e)
+}
+
+// String returns the source expression
+func (q *Query) String() string {
+	return q.expression
+}
@@ -0,0 +1,90 @@
+package query_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/query"
+)
+
+var _ = Describe("Query", func() {
+	structured := func(level entry.Level, message string, data map[string]interface{}) *entry.Entry {
+		return &entry.Entry{Structured: &entry.Structured{
+			Level:   level,
+			Message: message,
+			Data:    data,
+		}}
+	}
+
+	reconcile := structured(entry.LevelWarning, "reconciling machine", map[string]interface{}{
+		"controller":  "machine",
+		"attempts":    float64(3),
+		"retries":     "2",
+		"request":     map[string]interface{}{"name": "m-1"},
+		"k8s.io/name": "web",
+	})
+	reconcile.Source = &entry.Source{Namespace: "default", Pod: "manager-0"}
+
+	DescribeTable("matching entries",
+		func(expression string, e *entry.Entry, expected bool) {
+			q, err := query.Compile(expression)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q.Match(e)).To(Equal(expected))
+		},
+		Entry("the example", `level >= warning && data.controller == "machine" && msg =~ "reconcil"`, reconcile, true),
+		Entry("level below the minimum", `level >= error`, reconcile, false),
+		Entry("level aliases", `level == warn`, reconcile, true),
+		Entry("level of unstructured entries", `level == info`, &entry.Entry{Unstructured: "plain"}, true),
+		Entry("levels kept as written", `level == notice`, structured("notice", "started", nil), true),
+		Entry("levels kept as written with !=", `level != notice`, structured("critical", "failed", nil), true),
+		Entry("levels kept as written are not info", `level == info`, structured("notice", "started", nil), false),
+		Entry("message of unstructured entries", `message =~ '^pl'`, &entry.Entry{Unstructured: "plain"}, true),
+		Entry("nested data", `data.request.name == "m-1"`, reconcile, true),
+		Entry("data keys containing dots", `data.k8s.io/name == web`, reconcile, true),
+		Entry("numeric data", `data.attempts > 2 && data.attempts <= 3`, reconcile, true),
+		Entry("numeric strings", `data.retries >= 2`, reconcile, true),
+		Entry("missing data", `data.missing == "x"`, reconcile, false),
+		Entry("missing data with !=", `data.missing != "x"`, reconcile, true),
+		Entry("presence", `data.controller && !data.missing`, reconcile, true),
+		Entry("regex mismatch", `msg !~ "^reconcil"`, reconcile, false),
+		Entry("source", `source.pod == "manager-0" && source.namespace == default`, reconcile, true),
+		Entry("missing source", `source.pod == "manager-0"`, &entry.Entry{Unstructured: "plain"}, false),
+		Entry("or", `level == error || data.controller == machine`, reconcile, true),
+		Entry("precedence of && over ||", `level == error && msg =~ x || data.attempts == 3`, reconcile, true),
+		Entry("parentheses", `level == error && (msg =~ x || data.attempts == 3)`, reconcile, false),
+		Entry("escapes in double quotes", `msg == "a\"b"`, structured(entry.LevelInfo, `a"b`, nil), true),
+		Entry("raw single quotes", `msg =~ '\d+'`, structured(entry.LevelInfo, "42", nil), true),
+	)
+
+	It("matches everything with a nil query", func() {
+		var q *query.Query
+		Expect(q.Match(&entry.Entry{Unstructured: "plain"})).To(BeTrue())
+	})
+
+	DescribeTable("rejecting invalid expressions with their position",
+		func(expression string, offset int, message string) {
+			_, err := query.Compile(expression)
+			var syntaxErr *query.SyntaxError
+			Expect(errors.As(err, &syntaxErr)).To(BeTrue())
+			Expect(syntaxErr.Offset).To(Equal(offset))
+			Expect(syntaxErr.Message).To(ContainSubstring(message))
+		},
+		Entry("unknown field", `level >= info && lvl == x`, 17, `unknown field "lvl"`),
+		Entry("unknown level", `level >= verbose`, 9, "unknown level"),
+		Entry("invalid regex", `msg =~ "("`, 7, "invalid regular expression"),
+		Entry("missing value", `msg ==`, 6, "expected value"),
+		Entry("unclosed parenthesis", `(level == info`, 14, `expected ")"`),
+		Entry("trailing tokens", `level == info info`, 14, `unexpected "info"`),
+		Entry("unterminated string", `msg == "abc`, 7, "unterminated string"),
+		Entry("unexpected character", `msg == a | b`, 9, "unexpected character"),
+		Entry("unknown source field", `source.node == x`, 0, `unknown source field "node"`),
+	)
+
+	It("points at the error in the message", func() {
+		_, err := query.Compile(`level >= verbose`)
+		Expect(err).To(MatchError(ContainSubstring("column 10: unknown level: verbose\n\tlevel >= verbose\n\t         ^")))
+	})
+})
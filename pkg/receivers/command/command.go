@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// Names of the standard streams of the command used as entry.Source.Stream
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// terminateTimeout is how long the command may take to exit after SIGTERM on cancellation
+// before it is killed
+const terminateTimeout = 10 * time.Second
+
+var (
+	_ core.Receiver        = &Receiver{}
+	_ core.SignalForwarder = &Receiver{}
+)
+
+// ExitError reports that the command did not exit successfully
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// ReceiverOptions configures the command run by a Receiver
+type ReceiverOptions struct {
+	Command []string // command name followed by its arguments
+	Name    string   // source name of the entries; defaults to the base name of the command
+	Parser  receriver.Parser
+}
+
+// Receiver runs a command as a child process and parses its stdout and stderr separately,
+// tagging each entry with the stream it was written to
+type Receiver struct {
+	options ReceiverOptions
+
+	mu      sync.Mutex
+	process *os.Process
+}
+
+// NewReceiver creates a new command receiver
+func NewReceiver(options *ReceiverOptions) *Receiver {
+	r := &Receiver{options: *options}
+	if r.options.Name == "" {
+		r.options.Name = filepath.Base(options.Command[0])
+	}
+	return r
+}
+
+// Receive runs the command until it exits and reports the exit code as a final entry
+// The child does not share input: it runs in its own process group, where reading the
+// terminal would stop it, and signals reach it only through ForwardSignal
+// When ctx is canceled, the command is terminated and killed after terminateTimeout
+// An *ExitError is returned when the command does not exit successfully
+func (r *Receiver) Receive(ctx context.Context, input io.Reader, entriesChan chan<- *entry.Entry) error {
+	cmd := exec.CommandContext(ctx, r.options.Command[0], r.options.Command[1:]...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return signalProcessGroup(cmd.Process, syscall.SIGTERM)
+	}
+	// WaitDelay also closes the output of the command when processes it started in the
+	// background keep writing to it after it exits
+	cmd.WaitDelay = terminateTimeout
+	// exec copies the output through these pipes, which lets WaitDelay stop the copying
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+	r.mu.Lock()
+	r.process = cmd.Process
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go r.read(ctx, stdout, StreamStdout, entriesChan, &wg)
+	go r.read(ctx, stderr, StreamStderr, entriesChan, &wg)
+
+	// Wait returns once the output is copied, so the readers end after the rest of it
+	err := cmd.Wait()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// the command was terminated by cancellation
+		return ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+		return fmt.Errorf("failed to wait for command: %w", err)
+	}
+	code := exitCode(cmd.ProcessState)
+	select {
+	case entriesChan <- r.exitEntry(cmd.ProcessState, code):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// read parses a standard stream of the command until it is closed
+func (r *Receiver) read(ctx context.Context, pipe io.Reader, stream string, entriesChan chan<- *entry.Entry, wg *sync.WaitGroup) {
+	defer wg.Done()
+	receiver := receriver.NewReceiverWithOptions(&receriver.ReceiverOptions{
+		Parser: r.options.Parser,
+		Source: &entry.Source{Name: r.options.Name, Stream: stream},
+	})
+	if err := receiver.Receive(ctx, pipe, entriesChan); err != nil {
+		if ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "failed to read %s of %s: %v\n", stream, r.options.Name, err)
+		}
+		// keep the pipe flowing so that the command does not block on writes
+		io.Copy(io.Discard, pipe)
+	}
+}
+
+// exitEntry creates the entry reporting how the command exited
+func (r *Receiver) exitEntry(state *os.ProcessState, code int) *entry.Entry {
+	level := entry.LevelInfo
+	if code != 0 {
+		level = entry.LevelError
+	}
+	return &entry.Entry{
+		Structured: &entry.Structured{
+			Timestamp: time.Now(),
+			Level:     level,
+			Message:   fmt.Sprintf("%s exited: %s", r.options.Name, state),
+			Data: map[string]interface{}{
+				"exitCode": code,
+			},
+		},
+		Source: &entry.Source{Name: r.options.Name},
+	}
+}
+
+// ForwardSignal sends the signal to the command and the processes it started
+func (r *Receiver) ForwardSignal(sig os.Signal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.process == nil {
+		return
+	}
+	if err := signalProcessGroup(r.process, sig); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to forward %v to %s: %v\n", sig, r.options.Name, err)
+	}
+}
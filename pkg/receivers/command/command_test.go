@@ -0,0 +1,143 @@
+package command_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receivers/command"
+)
+
+// textParser is a test double that turns every line into an unstructured entry
+type textParser struct{}
+
+func (p *textParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	return []*entry.Entry{{Unstructured: line}}, nil
+}
+
+var _ = Describe("Command Receiver", func() {
+	var (
+		entriesChan chan *entry.Entry
+		errChan     chan error
+		ctx         context.Context
+		cancel      context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		entriesChan = make(chan *entry.Entry, 100)
+		errChan = make(chan error, 1)
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+	})
+
+	run := func(args ...string) *command.Receiver {
+		receiver := command.NewReceiver(&command.ReceiverOptions{
+			Command: args,
+			Parser:  &textParser{},
+		})
+		go func() {
+			errChan <- receiver.Receive(ctx, nil, entriesChan)
+		}()
+		return receiver
+	}
+
+	// receiveUntilExit collects entries until the exit entry of the command
+	receiveUntilExit := func() []*entry.Entry {
+		var entries []*entry.Entry
+		for {
+			var e *entry.Entry
+			Eventually(entriesChan).Should(Receive(&e))
+			entries = append(entries, e)
+			if e.Structured != nil {
+				return entries
+			}
+		}
+	}
+
+	It("should tag entries with the stream they were written to", func() {
+		run("sh", "-c", "echo out; echo err >&2")
+
+		entries := receiveUntilExit()
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[:2]).To(ConsistOf(
+			&entry.Entry{Unstructured: "out", Source: &entry.Source{Name: "sh", Stream: command.StreamStdout}},
+			&entry.Entry{Unstructured: "err", Source: &entry.Source{Name: "sh", Stream: command.StreamStderr}},
+		))
+		Eventually(errChan).Should(Receive(BeNil()))
+	})
+
+	It("should report a successful exit as the final entry", func() {
+		run("sh", "-c", "exit 0")
+
+		entries := receiveUntilExit()
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Source).To(Equal(&entry.Source{Name: "sh"}))
+		Expect(entries[0].Structured.Level).To(Equal(entry.LevelInfo))
+		Expect(entries[0].Structured.Data).To(HaveKeyWithValue("exitCode", 0))
+		Eventually(errChan).Should(Receive(BeNil()))
+	})
+
+	It("should report a failed exit with its code", func() {
+		run("sh", "-c", "echo failing; exit 3")
+
+		entries := receiveUntilExit()
+		Expect(entries).To(HaveLen(2))
+		exit := entries[1].Structured
+		Expect(exit.Level).To(Equal(entry.LevelError))
+		Expect(exit.Message).To(Equal("sh exited: exit status 3"))
+		Expect(exit.Data).To(HaveKeyWithValue("exitCode", 3))
+		Eventually(errChan).Should(Receive(Equal(&command.ExitError{Code: 3})))
+	})
+
+	It("should forward signals to the command", func() {
+		receiver := run("sh", "-c", `trap 'echo interrupted; exit 4' INT; echo ready; while :; do sleep 0.1; done`)
+
+		var e *entry.Entry
+		Eventually(entriesChan).Should(Receive(&e))
+		Expect(e.Unstructured).To(Equal("ready"))
+		receiver.ForwardSignal(os.Interrupt)
+
+		entries := receiveUntilExit()
+		Expect(entries[0].Unstructured).To(Equal("interrupted"))
+		Eventually(errChan).Should(Receive(Equal(&command.ExitError{Code: 4})))
+	})
+
+	It("should report the exit while a background process keeps the output open", func() {
+		receiver := run("sh", "-c", "echo started; sleep 60 &")
+		// the background process is left in the process group of the command
+		DeferCleanup(receiver.ForwardSignal, syscall.SIGTERM)
+
+		var e *entry.Entry
+		Eventually(entriesChan).Should(Receive(&e))
+		Expect(e.Unstructured).To(Equal("started"))
+		// the output is closed after terminateTimeout
+		Eventually(entriesChan, 15*time.Second).Should(Receive(&e))
+		Expect(e.Structured.Data).To(HaveKeyWithValue("exitCode", 0))
+		Eventually(errChan).Should(Receive(BeNil()))
+	})
+
+	It("should report a command that cannot be started", func() {
+		run("kutelog-command-that-does-not-exist")
+
+		var err error
+		Eventually(errChan).Should(Receive(&err))
+		Expect(err.Error()).To(ContainSubstring("failed to start command"))
+	})
+
+	It("should terminate the command when ctx is canceled", func() {
+		run("sh", "-c", "echo ready; while :; do sleep 0.1; done")
+
+		var e *entry.Entry
+		Eventually(entriesChan).Should(Receive(&e))
+		Expect(e.Unstructured).To(Equal("ready"))
+		cancel()
+
+		// SIGTERM ends the command long before it would be killed
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+	})
+})
@@ -0,0 +1,22 @@
+//go:build windows
+
+package command
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op since Windows has no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// signalProcessGroup kills the command since Windows cannot deliver other signals
+func signalProcessGroup(process *os.Process, sig os.Signal) error {
+	return process.Kill()
+}
+
+// exitCode returns the exit code of the command
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
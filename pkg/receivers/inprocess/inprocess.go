@@ -0,0 +1,188 @@
+package inprocess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+var _ core.Receiver = &Receiver{}
+
+// Receiver is fed by log calls made in the same process through the logr.LogSink and
+// slog.Handler it creates, so that no text is written and parsed in between
+type Receiver struct {
+	entries   chan *entry.Entry
+	closed    chan struct{} // closed by Close to end the input
+	closeOnce sync.Once
+	stopped   chan struct{} // closed when Receive returns, so that logging never blocks afterwards
+	stopOnce  sync.Once
+}
+
+// NewReceiver creates a new in-process receiver
+func NewReceiver() *Receiver {
+	return &Receiver{
+		entries: make(chan *entry.Entry, 1000),
+		closed:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Receive forwards logged entries to entriesChan until Close is called or ctx is canceled
+// input is not used since entries are logged directly
+func (r *Receiver) Receive(ctx context.Context, input io.Reader, entriesChan chan<- *entry.Entry) error {
+	defer r.stopOnce.Do(func() {
+		close(r.stopped)
+	})
+	for {
+		select {
+		case e := <-r.entries:
+			select {
+			case entriesChan <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case <-r.closed:
+			// forward what was logged before Close
+			for {
+				select {
+				case e := <-r.entries:
+					select {
+					case entriesChan <- e:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close ends the input; entries logged afterwards are dropped
+func (r *Receiver) Close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+}
+
+// send queues an entry, waiting while the buffer is full
+func (r *Receiver) send(e *entry.Entry) {
+	select {
+	case <-r.closed:
+		return
+	default:
+	}
+	select {
+	case r.entries <- e:
+	case <-r.closed:
+	case <-r.stopped:
+	}
+}
+
+// Session is a kutelog process fed by a Receiver
+type Session struct {
+	*Receiver
+	done chan error
+}
+
+// Start runs a kutelog process emitting to emitter, e.g. websocket.NewEmitter(),
+// until Stop is called or ctx is canceled
+// Signals are left to the caller, e.g. the test runner
+func Start(ctx context.Context, emitter core.Emitter) *Session {
+	receiver := NewReceiver()
+	process := core.NewProcess(&core.ProcessOptions{
+		Receiver:  receiver,
+		Emitter:   emitter,
+		ExitOnEOF: true,
+		Signals:   make(chan os.Signal),
+	})
+	session := &Session{
+		Receiver: receiver,
+		done:     make(chan error, 1),
+	}
+	go func() {
+		session.done <- process.Start(ctx)
+	}()
+	return session
+}
+
+// Stop emits the entries logged so far, closes the emitter and waits for the process to end
+func (s *Session) Stop() error {
+	s.Close()
+	return <-s.done
+}
+
+// caller returns the file:line of the function skip frames above the caller of caller
+func caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return shortCaller(file, line)
+}
+
+// shortCaller formats a source position like zap's ShortCallerEncoder,
+// keeping only the package directory, e.g. controller/reconciler.go:42
+func shortCaller(file string, line int) string {
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// stack returns the stack trace starting skip frames above the caller of stack,
+// formatted like zap's stacktrace field
+func stack(skip int) string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// jsonValue converts a logged value to one that can be sent to the viewer as JSON
+// The value is copied into generic JSON values as it is logged, since the caller may
+// change it while the entry is on its way, and since redaction and filters only walk
+// maps and slices of interface{}
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case logr.Marshaler:
+		v = value.MarshalLog()
+	case error:
+		return value.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	var copied interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return copied
+}
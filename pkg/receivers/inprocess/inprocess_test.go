@@ -0,0 +1,189 @@
+package inprocess_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receivers/inprocess"
+)
+
+// mockEmitter is a test double that records emitted entries
+type mockEmitter struct {
+	mu          sync.Mutex
+	entries     []*entry.Entry
+	closeCalled bool
+}
+
+func (m *mockEmitter) Init(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockEmitter) Emit(e *entry.Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, e)
+}
+
+func (m *mockEmitter) Close(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeCalled = true
+	return nil
+}
+
+var _ = Describe("In-process Receiver", func() {
+	var (
+		emitter *mockEmitter
+		session *inprocess.Session
+	)
+
+	BeforeEach(func() {
+		emitter = &mockEmitter{}
+		session = inprocess.Start(context.Background(), emitter)
+	})
+
+	// stop ends the session and returns the structured part of the emitted entries
+	stop := func() []*entry.Structured {
+		Expect(session.Stop()).To(Succeed())
+		Expect(emitter.closeCalled).To(BeTrue())
+		var result []*entry.Structured
+		for _, e := range emitter.entries {
+			result = append(result, e.Structured)
+		}
+		return result
+	}
+
+	Context("with logr", func() {
+		It("should convert log calls to entries", func() {
+			logger := session.Logger(&inprocess.LogSinkOptions{})
+			logger.WithName("controller").WithName("pod").WithValues("namespace", "default").
+				Info("reconciling", "name", "app-1", "replicas", 3)
+
+			entries := stop()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Level).To(Equal(entry.LevelInfo))
+			Expect(entries[0].Message).To(Equal("reconciling"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("logger", "controller.pod"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("namespace", "default"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("name", "app-1"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("replicas", float64(3)))
+			Expect(entries[0].Data).To(HaveKeyWithValue("caller", MatchRegexp(`^inprocess/inprocess_test\.go:\d+$`)))
+		})
+
+		It("should log V-levels up to the verbosity as debug", func() {
+			logger := session.Logger(&inprocess.LogSinkOptions{Verbosity: 1})
+			logger.V(1).Info("verbose")
+			logger.V(2).Info("too verbose")
+
+			entries := stop()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Message).To(Equal("verbose"))
+			Expect(entries[0].Level).To(Equal(entry.LevelDebug))
+			Expect(entries[0].Verbosity).To(Equal(1))
+		})
+
+		It("should log errors with a stack trace", func() {
+			logger := session.Logger(&inprocess.LogSinkOptions{})
+			logger.Error(errors.New("connection refused"), "failed to reconcile")
+
+			entries := stop()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Level).To(Equal(entry.LevelError))
+			Expect(entries[0].Data).To(HaveKeyWithValue("error", "connection refused"))
+			Expect(entries[0].Stack).To(HavePrefix("github.com/appthrust/kutelog/pkg/receivers/inprocess_test."))
+			Expect(entries[0].Stack).To(ContainSubstring("inprocess_test.go:"))
+		})
+
+		It("should copy values as they are logged", func() {
+			logger := session.Logger(&inprocess.LogSinkOptions{})
+			labels := map[string]string{"app": "web"}
+			logger.Info("labels", "labels", labels, "owner", struct{ Kind string }{Kind: "Deployment"})
+			labels["app"] = "changed after logging"
+
+			entries := stop()
+			Expect(entries[0].Data).To(HaveKeyWithValue("labels", map[string]interface{}{"app": "web"}))
+			Expect(entries[0].Data).To(HaveKeyWithValue("owner", map[string]interface{}{"Kind": "Deployment"}))
+		})
+
+		It("should convert values that cannot be sent as JSON to text", func() {
+			logger := session.Logger(&inprocess.LogSinkOptions{})
+			logger.Info("values", "err", errors.New("boom"), "fn", func() {})
+
+			entries := stop()
+			Expect(entries[0].Data).To(HaveKeyWithValue("err", "boom"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("fn", BeAssignableToTypeOf("")))
+		})
+	})
+
+	Context("with slog", func() {
+		It("should convert records to entries with nested groups", func() {
+			logger := slog.New(session.Handler(&inprocess.HandlerOptions{}))
+			logger.With("controller", "pod").WithGroup("req").
+				Info("reconciling", "name", "app-1", slog.Group("owner", "kind", "Deployment"))
+
+			entries := stop()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Level).To(Equal(entry.LevelInfo))
+			Expect(entries[0].Message).To(Equal("reconciling"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("controller", "pod"))
+			Expect(entries[0].Data).To(HaveKeyWithValue("req", map[string]interface{}{
+				"name": "app-1",
+				"owner": map[string]interface{}{
+					"kind": "Deployment",
+				},
+			}))
+			Expect(entries[0].Data).To(HaveKeyWithValue("caller", MatchRegexp(`^inprocess/inprocess_test\.go:\d+$`)))
+		})
+
+		It("should log levels down to the handler level", func() {
+			logger := slog.New(session.Handler(&inprocess.HandlerOptions{Level: slog.Level(-8)}))
+			logger.Log(context.Background(), slog.Level(-6), "verbose")
+			logger.Log(context.Background(), slog.Level(-10), "too verbose")
+			logger.Warn("warning")
+
+			entries := stop()
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Level).To(Equal(entry.LevelTrace))
+			Expect(entries[0].Verbosity).To(Equal(6))
+			Expect(entries[1].Level).To(Equal(entry.LevelWarning))
+		})
+	})
+
+	It("should stop forwarding the entries logged before Close when ctx is canceled", func() {
+		receiver := inprocess.NewReceiver()
+		logger := receiver.Logger(&inprocess.LogSinkOptions{})
+		logger.Info("first")
+		logger.Info("second")
+		receiver.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		done := make(chan error, 1)
+		go func() {
+			// nothing reads the entries
+			done <- receiver.Receive(ctx, nil, make(chan *entry.Entry))
+		}()
+		Eventually(done).Should(Receive(MatchError(context.Canceled)))
+	})
+
+	It("should not block logging after the session is stopped", func() {
+		logger := session.Logger(&inprocess.LogSinkOptions{})
+		Expect(session.Stop()).To(Succeed())
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 2000; i++ {
+				logger.Info("after stop")
+			}
+			close(done)
+		}()
+		Eventually(done).Should(BeClosed())
+		Expect(emitter.entries).To(BeEmpty())
+	})
+})
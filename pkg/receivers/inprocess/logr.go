@@ -0,0 +1,130 @@
+package inprocess
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+var (
+	_ logr.LogSink          = &logSink{}
+	_ logr.CallDepthLogSink = &logSink{}
+)
+
+// LogSinkOptions configures the logr.LogSink of a Receiver
+type LogSinkOptions struct {
+	// Verbosity is the highest V-level logged, e.g. 2 enables V(1) and V(2)
+	Verbosity int
+}
+
+// logSink converts logr calls to entries with the keys used by zap in controller-runtime
+type logSink struct {
+	receiver  *Receiver
+	verbosity int
+	name      string
+	values    []interface{}
+	depth     int
+}
+
+// LogSink creates a logr.LogSink sending log calls to the receiver
+func (r *Receiver) LogSink(options *LogSinkOptions) logr.LogSink {
+	return &logSink{
+		receiver:  r,
+		verbosity: options.Verbosity,
+	}
+}
+
+// Logger creates a logr.Logger sending log calls to the receiver
+func (r *Receiver) Logger(options *LogSinkOptions) logr.Logger {
+	return logr.New(r.LogSink(options))
+}
+
+func (s *logSink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+func (s *logSink) Enabled(level int) bool {
+	return level <= s.verbosity
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	e := s.entry(msg, keysAndValues)
+	if level > 0 {
+		e.Structured.Level = entry.LevelDebug
+		e.Structured.Verbosity = level
+	}
+	s.receiver.send(e)
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	e := s.entry(msg, keysAndValues)
+	e.Structured.Level = entry.LevelError
+	if err != nil {
+		e.Structured.Data["error"] = err.Error()
+	}
+	e.Structured.Stack = stack(s.depth + 1)
+	s.receiver.send(e)
+}
+
+// entry creates an info entry with the values of the sink and the call
+// It must be called directly by Info or Error for the caller to be right
+func (s *logSink) entry(msg string, keysAndValues []interface{}) *entry.Entry {
+	data := make(map[string]interface{})
+	if s.name != "" {
+		data["logger"] = s.name
+	}
+	if c := caller(s.depth + 2); c != "" {
+		data["caller"] = c
+	}
+	setValues(data, s.values)
+	setValues(data, keysAndValues)
+	return &entry.Entry{
+		Structured: &entry.Structured{
+			Timestamp: time.Now(),
+			Level:     entry.LevelInfo,
+			Message:   msg,
+			Data:      data,
+		},
+	}
+}
+
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	clone := *s
+	clone.values = append(append([]interface{}{}, s.values...), keysAndValues...)
+	return &clone
+}
+
+func (s *logSink) WithName(name string) logr.LogSink {
+	clone := *s
+	if clone.name == "" {
+		clone.name = name
+	} else {
+		clone.name += "." + name
+	}
+	return &clone
+}
+
+func (s *logSink) WithCallDepth(depth int) logr.LogSink {
+	clone := *s
+	clone.depth += depth
+	return &clone
+}
+
+// setValues stores logr key/value pairs in data
+// A key without value is stored with a nil value, as zap does
+func setValues(data map[string]interface{}, keysAndValues []interface{}) {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = jsonValue(keysAndValues[i+1])
+		}
+		data[key] = value
+	}
+}
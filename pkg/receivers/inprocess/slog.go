@@ -0,0 +1,136 @@
+package inprocess
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	slogparser "github.com/appthrust/kutelog/pkg/parsers/slog"
+)
+
+var _ slog.Handler = &handler{}
+
+// HandlerOptions configures the slog.Handler of a Receiver
+type HandlerOptions struct {
+	// Level is the minimum level logged, defaults to slog.LevelInfo
+	Level slog.Leveler
+}
+
+// handler converts slog records to entries, nesting groups as maps like slog.JSONHandler
+type handler struct {
+	receiver *Receiver
+	level    slog.Leveler
+	data     map[string]interface{} // attributes added by WithAttrs
+	groups   []string               // groups opened by WithGroup
+}
+
+// Handler creates a slog.Handler sending records to the receiver
+func (r *Receiver) Handler(options *HandlerOptions) slog.Handler {
+	level := options.Level
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &handler{
+		receiver: r,
+		level:    level,
+		data:     make(map[string]interface{}),
+	}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	data := cloneMap(h.data)
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		data["caller"] = shortCaller(frame.File, frame.Line)
+	}
+	group := openGroups(data, h.groups)
+	record.Attrs(func(attr slog.Attr) bool {
+		setAttr(group, attr)
+		return true
+	})
+
+	level := int(record.Level)
+	h.receiver.send(&entry.Entry{
+		Structured: &entry.Structured{
+			Timestamp: record.Time,
+			Level:     slogparser.LevelForValue(level),
+			Verbosity: slogparser.Verbosity(level),
+			Message:   record.Message,
+			Data:      data,
+		},
+	})
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.data = cloneMap(h.data)
+	group := openGroups(clone.data, h.groups)
+	for _, attr := range attrs {
+		setAttr(group, attr)
+	}
+	return &clone
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string{}, h.groups...), name)
+	return &clone
+}
+
+// openGroups returns the map of the innermost group, creating the groups as needed
+func openGroups(data map[string]interface{}, groups []string) map[string]interface{} {
+	for _, name := range groups {
+		group, ok := data[name].(map[string]interface{})
+		if !ok {
+			group = make(map[string]interface{})
+			data[name] = group
+		}
+		data = group
+	}
+	return data
+}
+
+// setAttr stores a slog attribute in data following the rules of the built-in handlers:
+// empty attributes are ignored and groups without a key are inlined
+func setAttr(data map[string]interface{}, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		data[attr.Key] = jsonValue(attr.Value.Any())
+		return
+	}
+	attrs := attr.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	group := data
+	if attr.Key != "" {
+		group = openGroups(data, []string{attr.Key})
+	}
+	for _, a := range attrs {
+		setAttr(group, a)
+	}
+}
+
+// cloneMap deep copies the nested maps of groups so that handlers do not share them
+func cloneMap(data map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if group, ok := value.(map[string]interface{}); ok {
+			value = cloneMap(group)
+		}
+		clone[key] = value
+	}
+	return clone
+}
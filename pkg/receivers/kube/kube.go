@@ -0,0 +1,183 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// resyncPeriod is how often pods are re-examined, restarting log streams that ended
+// while their container is still running (e.g. after a dropped connection)
+const resyncPeriod = 30 * time.Second
+
+var _ core.Receiver = &Receiver{}
+
+// ReceiverOptions configures which pods and containers a Receiver follows
+type ReceiverOptions struct {
+	Client        kubernetes.Interface
+	Namespace     string // namespace to watch; empty for all namespaces
+	LabelSelector string // label selector for pods, e.g. app=controller
+	Container     string // only follow containers with this name; empty for all
+	TailLines     *int64 // lines to show from containers already running at start; nil for all
+	Parser        receriver.Parser
+}
+
+// Receiver streams logs of all containers in pods matching a label selector via the Kubernetes API,
+// following new pods as they appear and tagging each entry with its pod, container and namespace
+type Receiver struct {
+	options   ReceiverOptions
+	startedAt time.Time
+
+	mu       sync.Mutex
+	streams  map[string]bool      // containers currently streamed, keyed by namespace/pod/container; false once the pod is deleted
+	lastRead map[string]time.Time // time of the end of the last stream per container
+}
+
+// NewReceiver creates a new Kubernetes logs receiver
+func NewReceiver(options *ReceiverOptions) *Receiver {
+	return &Receiver{
+		options:  *options,
+		streams:  make(map[string]bool),
+		lastRead: make(map[string]time.Time),
+	}
+}
+
+// Receive watches pods and streams their logs to entriesChan until ctx is canceled
+// input is not used since logs are read from the API server
+func (r *Receiver) Receive(ctx context.Context, input io.Reader, entriesChan chan<- *entry.Entry) error {
+	r.startedAt = time.Now()
+
+	factory := informers.NewSharedInformerFactoryWithOptions(r.options.Client, resyncPeriod,
+		informers.WithNamespace(r.options.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = r.options.LabelSelector
+		}),
+	)
+	informer := factory.Core().V1().Pods().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			r.sync(ctx, obj.(*corev1.Pod), entriesChan)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			r.sync(ctx, obj.(*corev1.Pod), entriesChan)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				r.forget(pod)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch pods: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to list pods: %w", ctx.Err())
+	}
+	<-ctx.Done() // follow pods until canceled
+	return ctx.Err()
+}
+
+// sync starts a log stream for every running container of the pod that is not streamed yet
+func (r *Receiver) sync(ctx context.Context, pod *corev1.Pod, entriesChan chan<- *entry.Entry) {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Running == nil {
+			continue
+		}
+		if r.options.Container != "" && status.Name != r.options.Container {
+			continue
+		}
+		source := &entry.Source{
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Container: status.Name,
+		}
+		key := pod.Namespace + "/" + pod.Name + "/" + status.Name
+
+		r.mu.Lock()
+		if _, ok := r.streams[key]; ok {
+			r.mu.Unlock()
+			continue
+		}
+		r.streams[key] = true
+		logOptions := &corev1.PodLogOptions{
+			Container: status.Name,
+			Follow:    true,
+		}
+		if lastRead, ok := r.lastRead[key]; ok {
+			// resume a stream that ended while the container kept running
+			logOptions.SinceTime = &metav1.Time{Time: lastRead}
+		} else if status.State.Running.StartedAt.Time.Before(r.startedAt) {
+			logOptions.TailLines = r.options.TailLines
+		}
+		r.mu.Unlock()
+
+		go r.stream(ctx, source, key, logOptions, entriesChan)
+	}
+}
+
+// forget drops what is remembered about the containers of a deleted pod
+func (r *Receiver) forget(pod *corev1.Pod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		key := pod.Namespace + "/" + pod.Name + "/" + container.Name
+		delete(r.lastRead, key)
+		if _, ok := r.streams[key]; ok {
+			// the stream ends with the container; it is not resumed
+			r.streams[key] = false
+		}
+	}
+}
+
+// stream reads the logs of a single container until the stream ends
+func (r *Receiver) stream(ctx context.Context, source *entry.Source, key string, logOptions *corev1.PodLogOptions, entriesChan chan<- *entry.Entry) {
+	defer func() {
+		r.mu.Lock()
+		if r.streams[key] {
+			r.lastRead[key] = time.Now()
+		}
+		delete(r.streams, key)
+		r.mu.Unlock()
+	}()
+
+	request := r.options.Client.CoreV1().Pods(source.Namespace).GetLogs(source.Pod, logOptions)
+	logs, err := request.Stream(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		// the container may not be ready yet; the next pod update or resync retries
+		fmt.Fprintf(os.Stderr, "failed to stream logs of %s: %v\n", key, err)
+		return
+	}
+	defer logs.Close()
+
+	receiver := receriver.NewReceiverWithOptions(&receriver.ReceiverOptions{
+		Parser: r.options.Parser,
+		Source: source,
+	})
+	if err := receiver.Receive(ctx, logs, entriesChan); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "failed to read logs of %s: %v\n", key, err)
+	}
+}
@@ -0,0 +1,217 @@
+package kube_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receivers/kube"
+)
+
+// textParser is a test double that turns every line into an unstructured entry
+type textParser struct{}
+
+func (p *textParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	return []*entry.Entry{{Unstructured: line}}, nil
+}
+
+func runningPod(namespace, name string, labels map[string]string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+	for _, container := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name: container,
+			State: corev1.ContainerState{
+				Running: &corev1.ContainerStateRunning{StartedAt: metav1.Now()},
+			},
+		})
+	}
+	return pod
+}
+
+var _ = Describe("Kube Receiver", func() {
+	var (
+		client      *fake.Clientset
+		entriesChan chan *entry.Entry
+		ctx         context.Context
+		cancel      context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		client = fake.NewSimpleClientset()
+		entriesChan = make(chan *entry.Entry, 100)
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+	})
+
+	// sources collects the sources of received entries until the expected number arrive
+	sources := func(n int) []entry.Source {
+		var result []entry.Source
+		for i := 0; i < n; i++ {
+			var e *entry.Entry
+			Eventually(entriesChan).Should(Receive(&e))
+			Expect(e.Unstructured).To(Equal("fake logs"))
+			Expect(e.Source).NotTo(BeNil())
+			result = append(result, *e.Source)
+		}
+		return result
+	}
+
+	It("should tag entries of all containers with their source", func() {
+		_, err := client.CoreV1().Pods("default").Create(ctx,
+			runningPod("default", "app-1", map[string]string{"app": "app"}, "manager", "sidecar"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client:        client,
+			Namespace:     "default",
+			LabelSelector: "app=app",
+			Parser:        &textParser{},
+		})
+		go receiver.Receive(ctx, nil, entriesChan)
+
+		Expect(sources(2)).To(ConsistOf(
+			entry.Source{Namespace: "default", Pod: "app-1", Container: "manager"},
+			entry.Source{Namespace: "default", Pod: "app-1", Container: "sidecar"},
+		))
+	})
+
+	It("should follow pods created after start", func() {
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client:        client,
+			Namespace:     "default",
+			LabelSelector: "app=app",
+			Parser:        &textParser{},
+		})
+		go receiver.Receive(ctx, nil, entriesChan)
+		Consistently(entriesChan, 200*time.Millisecond).ShouldNot(Receive())
+
+		_, err := client.CoreV1().Pods("default").Create(ctx,
+			runningPod("default", "app-2", map[string]string{"app": "app"}, "manager"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(sources(1)).To(ConsistOf(
+			entry.Source{Namespace: "default", Pod: "app-2", Container: "manager"},
+		))
+	})
+
+	It("should ignore pods not matching the selector or namespace", func() {
+		_, err := client.CoreV1().Pods("default").Create(ctx,
+			runningPod("default", "other", map[string]string{"app": "other"}, "manager"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.CoreV1().Pods("kube-system").Create(ctx,
+			runningPod("kube-system", "app-3", map[string]string{"app": "app"}, "manager"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client:        client,
+			Namespace:     "default",
+			LabelSelector: "app=app",
+			Parser:        &textParser{},
+		})
+		go receiver.Receive(ctx, nil, entriesChan)
+
+		Consistently(entriesChan, 500*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should only follow the given container", func() {
+		_, err := client.CoreV1().Pods("default").Create(ctx,
+			runningPod("default", "app-4", nil, "manager", "sidecar"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client:    client,
+			Container: "sidecar",
+			Parser:    &textParser{},
+		})
+		go receiver.Receive(ctx, nil, entriesChan)
+
+		Expect(sources(1)).To(ConsistOf(
+			entry.Source{Namespace: "default", Pod: "app-4", Container: "sidecar"},
+		))
+		Consistently(entriesChan, 200*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should wait for containers to start", func() {
+		pod := runningPod("default", "app-5", nil, "manager")
+		pod.Status.ContainerStatuses[0].State = corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"},
+		}
+		_, err := client.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client: client,
+			Parser: &textParser{},
+		})
+		go receiver.Receive(ctx, nil, entriesChan)
+		Consistently(entriesChan, 200*time.Millisecond).ShouldNot(Receive())
+
+		pod.Status.ContainerStatuses[0].State = corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{StartedAt: metav1.Now()},
+		}
+		_, err = client.CoreV1().Pods("default").UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(sources(1)).To(ConsistOf(
+			entry.Source{Namespace: "default", Pod: "app-5", Container: "manager"},
+		))
+	})
+
+	It("should not resume the logs of a deleted pod in a pod of the same name", func() {
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client: client,
+			Parser: &textParser{},
+		})
+		go receiver.Receive(ctx, nil, entriesChan)
+
+		_, err := client.CoreV1().Pods("default").Create(ctx, runningPod("default", "app-6", nil, "manager"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		sources(1)
+		// let the stream end, as the fake logs do right away
+		Consistently(entriesChan, 200*time.Millisecond).ShouldNot(Receive())
+
+		Expect(client.CoreV1().Pods("default").Delete(ctx, "app-6", metav1.DeleteOptions{})).To(Succeed())
+		Consistently(entriesChan, 200*time.Millisecond).ShouldNot(Receive())
+		_, err = client.CoreV1().Pods("default").Create(ctx, runningPod("default", "app-6", nil, "manager"), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		sources(1)
+
+		var logOptions []*corev1.PodLogOptions
+		for _, action := range client.Actions() {
+			if action.GetSubresource() == "log" {
+				logOptions = append(logOptions, action.(k8stesting.GenericAction).GetValue().(*corev1.PodLogOptions))
+			}
+		}
+		Expect(logOptions).To(HaveLen(2))
+		Expect(logOptions[1].SinceTime).To(BeNil())
+	})
+
+	It("should stop following pods when ctx is canceled", func() {
+		receiver := kube.NewReceiver(&kube.ReceiverOptions{
+			Client: client,
+			Parser: &textParser{},
+		})
+		done := make(chan error, 1)
+		go func() {
+			done <- receiver.Receive(ctx, nil, entriesChan)
+		}()
+		Consistently(done, 200*time.Millisecond).ShouldNot(Receive())
+
+		cancel()
+		Eventually(done).Should(Receive(MatchError(context.Canceled)))
+	})
+})
@@ -0,0 +1,205 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/appthrust/kutelog/pkg/core"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// maxLineBytes is the longest line of a session file, which holds a whole entry including its stack
+const maxLineBytes = 1 << 20
+
+var _ core.Receiver = &Receiver{}
+
+// ReceiverOptions configures what is replayed and how fast
+type ReceiverOptions struct {
+	// Path is the file to replay, optionally gzipped; "-" or empty for the input of Receive
+	Path string
+	// Parser parses files that are not sessions saved by kutelog, e.g. raw logs of a CI run
+	Parser receriver.Parser
+	// Speed is how much faster than recorded the entries are replayed, e.g. 1 for the original timing
+	// 0 replays everything at once
+	Speed float64
+	// MaxGap limits the wait between two entries, so that idle periods do not stall the replay
+	// 0 for no limit
+	MaxGap time.Duration
+}
+
+// Receiver replays a file written by the file emitter, or a raw log file through Parser,
+// pacing entries by their recorded timestamps
+type Receiver struct {
+	options ReceiverOptions
+}
+
+// NewReceiver creates a new replay receiver
+func NewReceiver(options *ReceiverOptions) *Receiver {
+	return &Receiver{options: *options}
+}
+
+// Receive replays the file to entriesChan, returning nil once everything is sent
+func (r *Receiver) Receive(ctx context.Context, input io.Reader, entriesChan chan<- *entry.Entry) error {
+	var source *entry.Source
+	if r.options.Path != "" && r.options.Path != "-" {
+		file, err := os.Open(r.options.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", r.options.Path, err)
+		}
+		defer file.Close()
+		input = file
+		source = &entry.Source{File: r.options.Path}
+	}
+
+	reader := bufio.NewReader(input)
+	if magic, _ := reader.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", r.options.Path, err)
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	// decode in the background so that reading ahead does not delay paced entries
+	decoded := make(chan *entry.Entry)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(decoded)
+		errChan <- r.decode(ctx, reader, source, decoded)
+	}()
+
+	p := &pacer{speed: r.options.Speed, maxGap: r.options.MaxGap}
+	for e := range decoded {
+		if err := p.wait(ctx, e); err != nil {
+			break
+		}
+		select {
+		case entriesChan <- e:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		for range decoded {
+			// the decoder stops sending once it sees the cancellation
+		}
+		return err
+	}
+	return <-errChan
+}
+
+// decode sends the entries of a session file, or of a raw log file parsed by the parser
+func (r *Receiver) decode(ctx context.Context, reader *bufio.Reader, source *entry.Source, decoded chan<- *entry.Entry) error {
+	// the first line tells whether the file is a saved session
+	first, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	rest := io.MultiReader(bytes.NewReader(first), reader)
+	if !isSessionLine(first) {
+		receiver := receriver.NewReceiverWithOptions(&receriver.ReceiverOptions{
+			Parser: r.options.Parser,
+			Source: source,
+		})
+		return receiver.Receive(ctx, rest, decoded)
+	}
+
+	scanner := bufio.NewScanner(rest)
+	scanner.Buffer(nil, maxLineBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		e := &entry.Entry{}
+		if err := json.Unmarshal(line, e); err != nil {
+			// e.g. the last line of a session that was killed while writing
+			e = &entry.Entry{Unstructured: string(line), Source: source}
+		}
+		select {
+		case decoded <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	return nil
+}
+
+// isSessionLine reports whether the line is an entry written by the file emitter
+func isSessionLine(line []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return false
+	}
+	_, structured := fields["structured"]
+	_, unstructured := fields["unstructured"]
+	if !structured && !unstructured {
+		return false
+	}
+	for key := range fields {
+		if !sessionKeys[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionKeys are the JSON keys of entry.Entry
+var sessionKeys = map[string]bool{
+	"structured":   true,
+	"unstructured": true,
+	"source":       true,
+	"raw":          true,
+}
+
+// pacer delays entries by the time between their timestamps
+type pacer struct {
+	speed  float64
+	maxGap time.Duration
+	last   time.Time // latest timestamp seen
+}
+
+// wait sleeps until the entry is due, returning early if ctx is canceled
+// Unstructured entries, and entries timed when they were read, have no recorded time
+// and are due immediately
+func (p *pacer) wait(ctx context.Context, e *entry.Entry) error {
+	if p.speed <= 0 || e.Structured == nil || e.Structured.Timestamp.IsZero() || e.Structured.ReadTime {
+		return nil
+	}
+	timestamp := e.Structured.Timestamp
+	if p.last.IsZero() {
+		p.last = timestamp
+		return nil
+	}
+	if !timestamp.After(p.last) {
+		// entries out of order, e.g. of merged pods, are due immediately
+		return nil
+	}
+	gap := timestamp.Sub(p.last)
+	p.last = timestamp
+	if p.maxGap > 0 && gap > p.maxGap {
+		gap = p.maxGap
+	}
+	timer := time.NewTimer(time.Duration(float64(gap) / p.speed))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,192 @@
+package replay_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/emitters/file"
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receivers/replay"
+)
+
+// textParser is a test double that turns every line into an unstructured entry
+type textParser struct{}
+
+func (p *textParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	return []*entry.Entry{{Unstructured: line}}, nil
+}
+
+var _ = Describe("Replay Receiver", func() {
+	var (
+		dir         string
+		entriesChan chan *entry.Entry
+		ctx         context.Context
+		cancel      context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		entriesChan = make(chan *entry.Entry, 100)
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+	})
+
+	start := time.Date(2025, 1, 30, 15, 52, 37, 0, time.UTC)
+	session := []*entry.Entry{
+		{
+			Structured: &entry.Structured{Timestamp: start, Level: entry.LevelInfo, Message: "starting"},
+			Source:     &entry.Source{Pod: "web-0"},
+		},
+		{Unstructured: "plain text log"},
+		{Structured: &entry.Structured{Timestamp: start.Add(200 * time.Millisecond), Level: entry.LevelError, Message: "failed"}},
+	}
+
+	// writeSession writes entries like the file emitter, gzipped if the path ends with .gz
+	writeSession := func(name string, entries []*entry.Entry) string {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		var encoder *json.Encoder
+		if strings.HasSuffix(name, ".gz") {
+			gz := gzip.NewWriter(f)
+			defer gz.Close()
+			encoder = json.NewEncoder(gz)
+		} else {
+			encoder = json.NewEncoder(f)
+		}
+		for _, e := range entries {
+			Expect(encoder.Encode(e)).To(Succeed())
+		}
+		return path
+	}
+
+	receive := func(options *replay.ReceiverOptions) []*entry.Entry {
+		receiver := replay.NewReceiver(options)
+		Expect(receiver.Receive(ctx, nil, entriesChan)).To(Succeed())
+		close(entriesChan)
+		var received []*entry.Entry
+		for e := range entriesChan {
+			received = append(received, e)
+		}
+		return received
+	}
+
+	It("replays a saved session as it was recorded", func() {
+		path := writeSession("session.jsonl", session)
+		Expect(receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}})).To(Equal(session))
+	})
+
+	It("replays a session written by the file emitter", func() {
+		recorded := []*entry.Entry{
+			{
+				Structured: &entry.Structured{Timestamp: start, Level: entry.LevelInfo, Message: "starting"},
+				Source:     &entry.Source{Pod: "web-0"},
+				Raw:        `{"level":"info","ts":1738252357,"msg":"starting"}`,
+			},
+			{Unstructured: "plain text log", Source: &entry.Source{Stream: "stderr"}},
+		}
+		emitter := file.NewEmitter(&file.EmitterOptions{Dir: dir})
+		Expect(emitter.Init(ctx)).To(Succeed())
+		for _, e := range recorded {
+			emitter.Emit(e)
+		}
+		path := emitter.Path()
+		Expect(emitter.Close(ctx)).To(Succeed())
+
+		Expect(receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}})).To(Equal(recorded))
+	})
+
+	It("replays a gzipped session", func() {
+		path := writeSession("session.jsonl.gz", session)
+		Expect(receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}})).To(Equal(session))
+	})
+
+	It("keeps a truncated last line as plain text", func() {
+		path := writeSession("session.jsonl", session[:1])
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteString(`{"structured":{"lev`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		received := receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}})
+		Expect(received).To(HaveLen(2))
+		Expect(received[1].Unstructured).To(Equal(`{"structured":{"lev`))
+	})
+
+	It("parses raw log files with the parser", func() {
+		path := filepath.Join(dir, "ci.log")
+		Expect(os.WriteFile(path, []byte("line 1\n{\"level\":\"info\"}\n"), 0o644)).To(Succeed())
+
+		received := receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}})
+		Expect(received).To(Equal([]*entry.Entry{
+			{Unstructured: "line 1", Source: &entry.Source{File: path}},
+			{Unstructured: `{"level":"info"}`, Source: &entry.Source{File: path}},
+		}))
+	})
+
+	It("reads the input when no path is given", func() {
+		receiver := replay.NewReceiver(&replay.ReceiverOptions{Parser: &textParser{}})
+		Expect(receiver.Receive(ctx, strings.NewReader("from stdin\n"), entriesChan)).To(Succeed())
+		Expect(<-entriesChan).To(Equal(&entry.Entry{Unstructured: "from stdin"}))
+	})
+
+	It("replays at the recorded pace scaled by speed", func() {
+		path := writeSession("session.jsonl", session)
+		started := time.Now()
+		receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}, Speed: 2})
+		Expect(time.Since(started)).To(BeNumerically("~", 100*time.Millisecond, 80*time.Millisecond))
+	})
+
+	It("does not pace by the times entries were read at", func() {
+		path := writeSession("session.jsonl", []*entry.Entry{
+			session[0],
+			// e.g. a panic grouped by the multiline parser when the log was read
+			{Structured: &entry.Structured{Timestamp: time.Now(), ReadTime: true, Level: entry.LevelPanic, Message: "panic: boom"}},
+			session[2],
+		})
+		started := time.Now()
+		Expect(receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}, Speed: 2, MaxGap: time.Second})).To(HaveLen(3))
+		Expect(time.Since(started)).To(BeNumerically("~", 100*time.Millisecond, 80*time.Millisecond))
+	})
+
+	It("limits the wait between entries", func() {
+		path := writeSession("session.jsonl", []*entry.Entry{
+			{Structured: &entry.Structured{Timestamp: start, Message: "before"}},
+			{Structured: &entry.Structured{Timestamp: start.Add(time.Hour), Message: "an hour later"}},
+		})
+		started := time.Now()
+		Expect(receive(&replay.ReceiverOptions{Path: path, Parser: &textParser{}, Speed: 1, MaxGap: 50 * time.Millisecond})).To(HaveLen(2))
+		Expect(time.Since(started)).To(BeNumerically("<", time.Second))
+	})
+
+	It("stops waiting when canceled", func() {
+		path := writeSession("session.jsonl", []*entry.Entry{
+			{Structured: &entry.Structured{Timestamp: start, Message: "before"}},
+			{Structured: &entry.Structured{Timestamp: start.Add(time.Hour), Message: "an hour later"}},
+		})
+		receiver := replay.NewReceiver(&replay.ReceiverOptions{Path: path, Parser: &textParser{}, Speed: 1})
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- receiver.Receive(ctx, nil, entriesChan)
+		}()
+		Eventually(entriesChan).Should(Receive())
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+		Expect(entriesChan).To(BeEmpty())
+	})
+
+	It("fails if the file cannot be opened", func() {
+		receiver := replay.NewReceiver(&replay.ReceiverOptions{Path: filepath.Join(dir, "missing.jsonl")})
+		Expect(receiver.Receive(ctx, nil, entriesChan)).To(MatchError(ContainSubstring("failed to open")))
+	})
+})
@@ -2,7 +2,9 @@ package receriver
 
 import (
 	"bufio"
+	"context"
 	"io"
+	"strings"
 
 	"github.com/appthrust/kutelog/pkg/core"
 	"github.com/appthrust/kutelog/pkg/entry"
@@ -12,18 +14,36 @@ var _ core.Receiver = &Receiver{}
 
 type Receiver struct {
 	parser Parser
+	source *entry.Source
+}
+
+// ReceiverOptions configures a Receiver
+type ReceiverOptions struct {
+	Parser Parser
+	// Source is attached to entries the parser did not attribute to a source
+	// Leave nil for the default input
+	Source *entry.Source
 }
 
 func NewReceiver(parser Parser) *Receiver {
-	return &Receiver{parser: parser}
+	return NewReceiverWithOptions(&ReceiverOptions{Parser: parser})
+}
+
+// NewReceiverWithOptions creates a new receiver with the given options
+func NewReceiverWithOptions(options *ReceiverOptions) *Receiver {
+	return &Receiver{
+		parser: options.Parser,
+		source: options.Source,
+	}
 }
 
-func (r *Receiver) Receive(input io.Reader, entriesChan chan<- *entry.Entry, errChan chan<- error) {
+// Receive parses input until EOF, sending entries to entriesChan
+// Cancellation of ctx takes effect once the pending read of input returns
+func (r *Receiver) Receive(ctx context.Context, input io.Reader, entriesChan chan<- *entry.Entry) error {
 	scanner := bufio.NewScanner(input)
 
-	var currentLine string // current line being processed
-	var nextLine string    // next line
-	var hasPeeked bool     // whether peek has been performed
+	var nextLine string // next line
+	var hasPeeked bool  // whether peek has been performed
 
 	// internal function to read next line
 	readNextLine := func() (string, error) {
@@ -49,39 +69,54 @@ func (r *Receiver) Receive(input io.Reader, entriesChan chan<- *entry.Entry, err
 		return nextLine, nil
 	}
 
+	var consumed []string // lines consumed by the parser after the current line
 	consumeLine := func() {
+		if hasPeeked {
+			consumed = append(consumed, nextLine)
+		}
 		hasPeeked = false // reset for next peek to read new line
 	}
 
 	// main loop
 	for {
-		// read line
-		line, err := readNextLine()
-		if err != nil {
-			if err != io.EOF {
-				errChan <- err
-				return
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var currentLine string // current line being processed
+		if hasPeeked {
+			// use the peeked but not consumed line from previous Parser as currentLine
+			currentLine = nextLine
+			hasPeeked = false // reset state for new Parser
+		} else {
+			line, err := readNextLine()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
 			}
-			// wait for new input on EOF
-			continue
+			currentLine = line
 		}
-		currentLine = line
 
+		consumed = consumed[:0]
 		entries, err := r.parser.Parse(currentLine, peekLine, consumeLine)
 		if err != nil {
-			errChan <- err
-			return
+			return err
 		}
 
-		for _, entry := range entries {
-			entriesChan <- entry
+		// the lines belong to the first entry, so that exporting them reproduces the input once
+		if len(entries) > 0 && entries[0].Structured != nil && entries[0].Raw == "" {
+			entries[0].Raw = strings.Join(append([]string{currentLine}, consumed...), "\n")
 		}
-
-		// get next line (if peeked)
-		if hasPeeked {
-			// use the peeked but not consumed line from previous Parser as next currentLine
-			currentLine = nextLine
-			hasPeeked = false // reset state for new Parser
+		for _, entry := range entries {
+			if entry.Source == nil {
+				entry.Source = r.source
+			}
+			select {
+			case entriesChan <- entry:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 	}
 }
@@ -0,0 +1,13 @@
+package receriver_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReceriver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Receriver Suite")
+}
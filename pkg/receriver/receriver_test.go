@@ -0,0 +1,103 @@
+package receriver_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+	"github.com/appthrust/kutelog/pkg/receriver"
+)
+
+// joinParser is a test double that joins indented continuation lines to the previous line,
+// peeking at the line that follows the continuation to decide when the entry ends
+type joinParser struct{}
+
+func (p *joinParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	for {
+		next, err := peekLine()
+		if err != nil || !strings.HasPrefix(next, " ") {
+			return []*entry.Entry{{Unstructured: line}}, nil
+		}
+		line += "\n" + next
+		consumeLine()
+	}
+}
+
+// structuredParser is a test double that parses every line into a structured entry
+// whose message is the line with its indented continuation lines
+type structuredParser struct{ joinParser }
+
+func (p *structuredParser) Parse(line string, peekLine func() (string, error), consumeLine func()) ([]*entry.Entry, error) {
+	entries, err := p.joinParser.Parse(line, peekLine, consumeLine)
+	if err != nil {
+		return nil, err
+	}
+	message, _, _ := strings.Cut(entries[0].Unstructured, "\n")
+	return []*entry.Entry{{Structured: &entry.Structured{Message: message}}}, nil
+}
+
+var _ = Describe("Receiver", func() {
+	var entriesChan chan *entry.Entry
+
+	BeforeEach(func() {
+		entriesChan = make(chan *entry.Entry, 100)
+	})
+
+	received := func() []string {
+		var lines []string
+		for len(entriesChan) > 0 {
+			lines = append(lines, (<-entriesChan).Unstructured)
+		}
+		return lines
+	}
+
+	It("should parse the line peeked but not consumed by the previous entry", func() {
+		receiver := receriver.NewReceiver(&joinParser{})
+		input := "first\n  continued\nsecond\nthird\n  continued\n"
+
+		Expect(receiver.Receive(context.Background(), strings.NewReader(input), entriesChan)).To(Succeed())
+		Expect(received()).To(Equal([]string{
+			"first\n  continued",
+			"second",
+			"third\n  continued",
+		}))
+	})
+
+	It("should stop sending entries once ctx is canceled", func() {
+		receiver := receriver.NewReceiver(&joinParser{})
+		ctx, cancel := context.WithCancel(context.Background())
+		blocked := make(chan *entry.Entry) // nobody receives
+
+		done := make(chan error, 1)
+		go func() {
+			done <- receiver.Receive(ctx, strings.NewReader("first\nsecond\n"), blocked)
+		}()
+		Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+		cancel()
+		Eventually(done).Should(Receive(MatchError(context.Canceled)))
+	})
+
+	It("should tag entries with the source of the receiver", func() {
+		source := &entry.Source{Name: "controller"}
+		receiver := receriver.NewReceiverWithOptions(&receriver.ReceiverOptions{
+			Parser: &joinParser{},
+			Source: source,
+		})
+
+		Expect(receiver.Receive(context.Background(), strings.NewReader("line\n"), entriesChan)).To(Succeed())
+		Expect(entriesChan).To(Receive(Equal(&entry.Entry{Unstructured: "line", Source: source})))
+	})
+
+	It("should keep the original lines of structured entries", func() {
+		receiver := receriver.NewReceiver(&structuredParser{})
+		input := "first\n  continued\nsecond\n"
+
+		Expect(receiver.Receive(context.Background(), strings.NewReader(input), entriesChan)).To(Succeed())
+		Expect((<-entriesChan).Raw).To(Equal("first\n  continued"))
+		Expect((<-entriesChan).Raw).To(Equal("second"))
+	})
+})
@@ -0,0 +1,101 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sensitiveKeys are the data keys masked by default, normalized by normalizeKey
+var sensitiveKeys = map[string]bool{
+	"authorization":      true,
+	"password":           true,
+	"passwd":             true,
+	"token":              true,
+	"accesstoken":        true,
+	"refreshtoken":       true,
+	"idtoken":            true,
+	"authtoken":          true,
+	"bearertoken":        true,
+	"clientsecret":       true,
+	"clientkeydata":      true, // kubeconfig client-key-data
+	"apikey":             true,
+	"secretkey":          true,
+	"secretaccesskey":    true,
+	"awssecretaccesskey": true,
+	"privatekey":         true,
+}
+
+// normalizeKey makes client-key-data, client_key_data and clientKeyData the same key
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+}
+
+// sensitiveKey matches the names of sensitive keys in text
+const sensitiveKey = `(?:passw(?:or)?d|(?:access|refresh|id|auth|bearer)?[-_]?token|client[-_]?secret|client[-_]?key[-_]?data|api[-_]?key|(?:aws[-_]?)?secret[-_]?(?:access[-_]?)?key|private[-_]?key)`
+
+// textDetectors mask secrets in text, in order; where a detector has groups only the groups are masked
+var textDetectors = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	// JWTs, e.g. service account tokens
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA|AIDA|AROA)[0-9A-Z]{16}\b`),
+	// Authorization headers, keeping the scheme
+	regexp.MustCompile(`(?i)authorization["']?\s*[:=]\s*["']?(?:(?:bearer|basic|token)\s+)?([^\s"',;]+)`),
+	regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{16,})`),
+	// key=value as in logfmt, query strings and environment variables
+	regexp.MustCompile(`(?i)` + sensitiveKey + `\s*=\s*([^\s"'&,;)}\]]+)`),
+	// "key": "value" as in JSON
+	regexp.MustCompile(`(?i)"[\w-]*` + sensitiveKey + `"\s*:\s*"([^"]*)"`),
+	// key: value as in YAML, e.g. a kubeconfig
+	regexp.MustCompile(`(?im)^\s*-?\s*[\w-]*` + sensitiveKey + `:[ \t]+(\S+)`),
+}
+
+var (
+	secretKindPattern     = regexp.MustCompile(`"kind"\s*:\s*"Secret"|(?m:^\s*kind:\s*Secret\s*$)`)
+	secretJSONDataPattern = regexp.MustCompile(`"(?:data|stringData)"\s*:\s*\{[^{}]*\}`)
+	jsonStringValue       = regexp.MustCompile(`("[^"]*"\s*:\s*)"[^"]*"`)
+	yamlDataLine          = regexp.MustCompile(`^(\s*)(?:data|stringData):\s*$`)
+	yamlKeyLine           = regexp.MustCompile(`^(\s*[^\s:][^:]*:\s+)\S`)
+)
+
+// redactText applies the built-in detectors to text
+func redactText(s string) string {
+	for _, re := range textDetectors {
+		s = maskMatches(re, s)
+	}
+	if secretKindPattern.MatchString(s) {
+		s = redactSecretText(s)
+	}
+	return s
+}
+
+// redactSecretText masks the data of a Secret printed as JSON or YAML, keeping the keys
+func redactSecretText(s string) string {
+	s = secretJSONDataPattern.ReplaceAllStringFunc(s, func(data string) string {
+		return jsonStringValue.ReplaceAllString(data, `$1"`+Mask+`"`)
+	})
+
+	lines := strings.Split(s, "\n")
+	indent := -1 // indentation of the data key while in its block
+	for i, line := range lines {
+		if indent >= 0 {
+			trimmed := strings.TrimLeft(line, " \t")
+			if trimmed != "" && len(line)-len(trimmed) <= indent {
+				indent = -1
+			} else if trimmed != "" {
+				if match := yamlKeyLine.FindStringSubmatch(line); match != nil {
+					lines[i] = match[1] + Mask
+				} else {
+					// continued value, e.g. of a block scalar
+					lines[i] = line[:len(line)-len(trimmed)] + Mask
+				}
+				continue
+			}
+		}
+		if match := yamlDataLine.FindStringSubmatch(line); match != nil {
+			indent = len(match[1])
+		}
+	}
+	return strings.Join(lines, "\n")
+}
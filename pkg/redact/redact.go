@@ -0,0 +1,223 @@
+// Package redact masks secrets in log entries before they are shown or saved
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/appthrust/kutelog/pkg/entry"
+)
+
+// Mask replaces redacted values
+const Mask = "[REDACTED]"
+
+// Options configures what is redacted besides the built-in detectors
+type Options struct {
+	// Keys are data keys whose values are masked, compared case-insensitively
+	// A dotted path such as spec.credentials matches from the top of the data,
+	// a single key such as apiKey matches at any depth
+	// In text the values of the keys are masked in JSON, logfmt and YAML form
+	Keys []string
+	// Patterns are regular expressions masked in every string
+	// If a pattern has groups, only the groups are masked, e.g. `session=(\w+)`
+	Patterns []string
+	// DisableBuiltins turns off the built-in detectors, leaving only Keys and Patterns
+	DisableBuiltins bool
+}
+
+// Redactor masks secrets in entries, safe for concurrent use
+type Redactor struct {
+	paths    map[string]bool  // dotted paths from the top of the data
+	keys     map[string]bool  // keys matching at any depth
+	keyText  []*regexp.Regexp // configured keys as they appear in text
+	patterns []*regexp.Regexp
+	builtins bool
+}
+
+// New creates a redactor, failing if a pattern is not a valid regular expression
+func New(options *Options) (*Redactor, error) {
+	r := &Redactor{
+		paths:    map[string]bool{},
+		keys:     map[string]bool{},
+		builtins: !options.DisableBuiltins,
+	}
+	for _, key := range options.Keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if strings.Contains(key, ".") {
+			r.paths[key] = true
+		} else {
+			r.keys[key] = true
+		}
+		r.keyText = append(r.keyText, keyTextPatterns(key)...)
+	}
+	for _, pattern := range options.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Entry returns a copy of the entry with secrets masked, leaving the entry itself unchanged
+func (r *Redactor) Entry(e *entry.Entry) *entry.Entry {
+	if e == nil {
+		return nil
+	}
+	redacted := *e
+	redacted.Unstructured = r.String(e.Unstructured)
+	redacted.Raw = r.String(e.Raw)
+	if e.Structured != nil {
+		structured := *e.Structured
+		structured.Message = r.String(structured.Message)
+		structured.Stack = r.String(structured.Stack)
+		if structured.Data != nil {
+			structured.Data = r.object(structured.Data, "")
+		}
+		redacted.Structured = &structured
+	}
+	return &redacted
+}
+
+// String masks secrets in text
+func (r *Redactor) String(s string) string {
+	if s == "" {
+		return s
+	}
+	if r.builtins {
+		s = redactText(s)
+	}
+	for _, re := range r.keyText {
+		s = maskMatches(re, s)
+	}
+	for _, re := range r.patterns {
+		s = maskMatches(re, s)
+	}
+	return s
+}
+
+// keyTextPatterns matches the value of a configured key in JSON, logfmt and YAML text
+// Text does not tell the path of a key, so a dotted path matches by its last key
+func keyTextPatterns(key string) []*regexp.Regexp {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	quoted := regexp.QuoteMeta(key)
+	return []*regexp.Regexp{
+		// "key": "value" or "key": 123
+		regexp.MustCompile(`(?i)"` + quoted + `"\s*:\s*(?:"([^"]*)"|([^\s"{\[,}\]]+))`),
+		// key=value or key="value"
+		regexp.MustCompile(`(?i)(?:^|[^\w-])` + quoted + `=(?:"([^"]*)"|([^\s"'&,;)}\]]+))`),
+		// key: value
+		regexp.MustCompile(`(?im)^\s*-?\s*` + quoted + `:[ \t]+(\S[^\r\n]*)`),
+	}
+}
+
+// object returns a copy of the data with secrets masked; path is the dotted path of data
+func (r *Redactor) object(data map[string]interface{}, path string) map[string]interface{} {
+	secret := r.builtins && isSecret(data)
+	redacted := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		switch {
+		case r.sensitive(key, keyPath):
+			redacted[key] = Mask
+		case secret && (key == "data" || key == "stringData"):
+			redacted[key] = maskValues(value)
+		default:
+			redacted[key] = r.value(value, keyPath)
+		}
+	}
+	return redacted
+}
+
+func (r *Redactor) value(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.String(v)
+	case map[string]interface{}:
+		return r.object(v, path)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.value(item, path)
+		}
+		return redacted
+	case nil, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return value
+	}
+	// other values, e.g. http.Header or structs, are walked as the JSON they are sent as
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Mask
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return Mask
+	}
+	return r.value(generic, path)
+}
+
+// sensitive reports whether the value of a key is masked as a whole
+func (r *Redactor) sensitive(key, path string) bool {
+	key = strings.ToLower(key)
+	if r.keys[key] || r.paths[strings.ToLower(path)] {
+		return true
+	}
+	return r.builtins && sensitiveKeys[normalizeKey(key)]
+}
+
+// isSecret reports whether an object is a Kubernetes Secret, whose data is masked
+func isSecret(data map[string]interface{}) bool {
+	kind, _ := data["kind"].(string)
+	return kind == "Secret"
+}
+
+// maskValues keeps the keys of Secret data, which tell what the Secret holds, and masks the values
+func maskValues(value interface{}) interface{} {
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return Mask
+	}
+	masked := make(map[string]interface{}, len(data))
+	for key := range data {
+		masked[key] = Mask
+	}
+	return masked
+}
+
+// maskMatches masks the matches of re in s, or only their groups if re has any
+func maskMatches(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, Mask)
+	}
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		for i := 2; i < len(match); i += 2 {
+			start, end := match[i], match[i+1]
+			if start < last || start == end {
+				// unmatched, empty or nested in a group already masked
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(Mask)
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package stack
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Editors are the URL templates of editors known by name
+// {file} is replaced by the absolute path of the file and {line} by the line number
+var Editors = map[string]string{
+	"vscode":   "vscode://file{file}:{line}",
+	"cursor":   "cursor://file{file}:{line}",
+	"idea":     "idea://open?file={file}&line={line}",
+	"goland":   "goland://open?file={file}&line={line}",
+	"sublime":  "subl://open?url=file://{file}&line={line}",
+	"textmate": "txmt://open?url=file://{file}&line={line}",
+}
+
+// PathMapping rewrites the start of file paths, e.g. from the working directory
+// of a container to the checkout of the same sources on the local machine
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// ParsePathMapping parses a mapping written as from=to, e.g. /workspace=/home/me/project
+func ParsePathMapping(s string) (PathMapping, error) {
+	from, to, ok := strings.Cut(s, "=")
+	if !ok || from == "" || to == "" {
+		return PathMapping{}, fmt.Errorf("invalid path mapping %q: expected from=to", s)
+	}
+	return PathMapping{From: from, To: to}, nil
+}
+
+// LinkerOptions configures the editor links of frames
+type LinkerOptions struct {
+	// Editor is the name of one of Editors, or a URL template containing {file}
+	Editor string
+	// PathMap rewrites file paths before they are linked; the first matching mapping applies
+	PathMap []PathMapping
+}
+
+// Linker sets the editor URLs of frames, safe for concurrent use
+type Linker struct {
+	template string
+	pathMap  []PathMapping
+}
+
+// NewLinker creates a linker, failing if the editor is neither known nor a template
+func NewLinker(options *LinkerOptions) (*Linker, error) {
+	template, ok := Editors[options.Editor]
+	if !ok {
+		if !strings.Contains(options.Editor, "{file}") {
+			return nil, fmt.Errorf("unknown editor %q: use a URL template containing {file} or one of vscode, cursor, idea, goland, sublime and textmate", options.Editor)
+		}
+		template = options.Editor
+	}
+	return &Linker{template: template, pathMap: options.PathMap}, nil
+}
+
+// Link sets the URL of the frames whose file is an absolute path once mapped
+// A nil Linker leaves the frames unchanged
+func (l *Linker) Link(frames []Frame) {
+	if l == nil {
+		return
+	}
+	for i := range frames {
+		if frames[i].File == "" {
+			continue
+		}
+		file := l.mapPath(frames[i].File)
+		if !isAbs(file) {
+			// e.g. Main.java, which cannot be found without the source path
+			continue
+		}
+		frames[i].URL = strings.NewReplacer(
+			"{file}", escapePath(file),
+			"{line}", strconv.Itoa(frames[i].Line),
+		).Replace(l.template)
+	}
+}
+
+func (l *Linker) mapPath(file string) string {
+	for _, mapping := range l.pathMap {
+		if rest, ok := strings.CutPrefix(file, mapping.From); ok && (rest == "" || rest[0] == '/' || strings.HasSuffix(mapping.From, "/")) {
+			return mapping.To + rest
+		}
+	}
+	return file
+}
+
+// isAbs reports whether a path is absolute on Unix or on Windows, where the viewer may run
+// even if the logs come from a Linux container
+func isAbs(file string) bool {
+	return strings.HasPrefix(file, "/") || (len(file) > 2 && file[1] == ':' && (file[2] == '\\' || file[2] == '/'))
+}
+
+// escapePath turns a path into the path of a URL, e.g. C:\src\main.go into /C:/src/main.go
+func escapePath(file string) string {
+	file = strings.ReplaceAll(file, "\\", "/")
+	if !strings.HasPrefix(file, "/") {
+		file = "/" + file
+	}
+	return (&url.URL{Path: file}).EscapedPath()
+}
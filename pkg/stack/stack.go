@@ -0,0 +1,81 @@
+// Package stack parses the stack traces of log entries into frames that link to their source
+package stack
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Frame is a call in a stack trace
+type Frame struct {
+	Function  string `json:"function,omitempty"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Goroutine int    `json:"goroutine,omitempty"` // ID of the goroutine in a Go panic; 0 if unknown
+	URL       string `json:"url,omitempty"`       // link opening File in an editor, set by a Linker
+}
+
+var (
+	goroutineRegex = regexp.MustCompile(`^goroutine (\d+) \[`)
+	// goFileRegex matches the position line below a Go function, e.g. "\t/app/main.go:8 +0x1d"
+	goFileRegex = regexp.MustCompile(`^\s+(\S.*?):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	// goArgsRegex matches the arguments of a Go function, e.g. pkg.(*T).M(0x1, 0x2)
+	goArgsRegex = regexp.MustCompile(`^(.*?)\([^()]*\)$`)
+	// createdByRegex matches the function that started a goroutine
+	createdByRegex = regexp.MustCompile(`^created by (\S+?)(?: in goroutine \d+)?$`)
+
+	// javaFrameRegex matches e.g. "\tat com.example.Main.main(Main.java:10)"
+	javaFrameRegex = regexp.MustCompile(`^\s+at (\S+)\(([^:()]*)(?::(\d+))?\)$`)
+	// pythonFrameRegex matches e.g. `  File "/app/main.py", line 3, in <module>`
+	pythonFrameRegex = regexp.MustCompile(`^\s+File "([^"]+)", line (\d+)(?:, in (.+))?$`)
+)
+
+// Parse returns the frames of a stack trace, skipping lines that are not part of a frame
+func Parse(stack string) []Frame {
+	var frames []Frame
+	var function string // Go function waiting for its position line
+	goroutine := 0
+	for _, line := range strings.Split(stack, "\n") {
+		if m := goFileRegex.FindStringSubmatch(line); m != nil && function != "" {
+			number, _ := strconv.Atoi(m[2])
+			frames = append(frames, Frame{Function: function, File: m[1], Line: number, Goroutine: goroutine})
+			function = ""
+			continue
+		}
+		function = ""
+
+		if m := javaFrameRegex.FindStringSubmatch(line); m != nil {
+			frame := Frame{Function: m[1]}
+			if m[3] != "" {
+				// e.g. Native Method or Unknown Source have no line
+				frame.File = m[2]
+				frame.Line, _ = strconv.Atoi(m[3])
+			}
+			frames = append(frames, frame)
+			continue
+		}
+		if m := pythonFrameRegex.FindStringSubmatch(line); m != nil {
+			number, _ := strconv.Atoi(m[2])
+			frames = append(frames, Frame{Function: m[3], File: m[1], Line: number})
+			continue
+		}
+
+		if m := goroutineRegex.FindStringSubmatch(line); m != nil {
+			goroutine, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		// a Go function, e.g. main.main() in a panic or main.main in a zap stacktrace
+		if m := createdByRegex.FindStringSubmatch(line); m != nil {
+			function = m[1]
+		} else if m := goArgsRegex.FindStringSubmatch(line); m != nil {
+			function = m[1]
+		} else if !strings.Contains(line, " ") {
+			function = line
+		}
+	}
+	return frames
+}
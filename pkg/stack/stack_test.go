@@ -0,0 +1,141 @@
+package stack_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/appthrust/kutelog/pkg/stack"
+)
+
+var _ = Describe("Stack", func() {
+	DescribeTable("Parse",
+		func(trace string, expected []stack.Frame) {
+			Expect(stack.Parse(trace)).To(Equal(expected))
+		},
+		Entry("zap stacktrace",
+			"main.main\n\t/app/main.go:8\nruntime.main\n\t/usr/local/go/src/runtime/proc.go:272",
+			[]stack.Frame{
+				{Function: "main.main", File: "/app/main.go", Line: 8},
+				{Function: "runtime.main", File: "/usr/local/go/src/runtime/proc.go", Line: 272},
+			},
+		),
+		Entry("Go panic with goroutines",
+			"goroutine 7 [running]:\n"+
+				"sigs.k8s.io/controller-runtime/pkg/internal/controller.(*Controller).Reconcile.func1()\n"+
+				"\t/go/pkg/mod/controller.go:111 +0x1e5\n"+
+				"created by sigs.k8s.io/controller-runtime/pkg/manager.(*runnableGroup).reconcile in goroutine 1\n"+
+				"\t/go/pkg/mod/runnable_group.go:223 +0x4d\n"+
+				"\n"+
+				"goroutine 1 [chan receive]:\n"+
+				"main.lookup(...)\n"+
+				"\t/app/main.go:12\n"+
+				"main.main()\n"+
+				"\t/app/main.go:20 +0x3c\n"+
+				"exit status 2",
+			[]stack.Frame{
+				{Function: "sigs.k8s.io/controller-runtime/pkg/internal/controller.(*Controller).Reconcile.func1", File: "/go/pkg/mod/controller.go", Line: 111, Goroutine: 7},
+				{Function: "sigs.k8s.io/controller-runtime/pkg/manager.(*runnableGroup).reconcile", File: "/go/pkg/mod/runnable_group.go", Line: 223, Goroutine: 7},
+				{Function: "main.lookup", File: "/app/main.go", Line: 12, Goroutine: 1},
+				{Function: "main.main", File: "/app/main.go", Line: 20, Goroutine: 1},
+			},
+		),
+		Entry("Java stack trace",
+			"\tat com.example.Client.connect(Client.java:42)\n"+
+				"Caused by: java.net.ConnectException: Connection refused\n"+
+				"\tat java.base/sun.nio.ch.Net.connect0(Native Method)\n"+
+				"\t... 2 more",
+			[]stack.Frame{
+				{Function: "com.example.Client.connect", File: "Client.java", Line: 42},
+				{Function: "java.base/sun.nio.ch.Net.connect0"},
+			},
+		),
+		Entry("Python traceback",
+			"Traceback (most recent call last):\n"+
+				"  File \"/app/main.py\", line 3, in <module>\n"+
+				"    int(\"x\")",
+			[]stack.Frame{
+				{Function: "<module>", File: "/app/main.py", Line: 3},
+			},
+		),
+		Entry("text without frames", "something went wrong\n\tdetails: none", nil),
+	)
+
+	Describe("Linker", func() {
+		link := func(options *stack.LinkerOptions, frames ...stack.Frame) []stack.Frame {
+			linker, err := stack.NewLinker(options)
+			Expect(err).NotTo(HaveOccurred())
+			linker.Link(frames)
+			return frames
+		}
+
+		It("links absolute paths to the editor", func() {
+			frames := link(&stack.LinkerOptions{Editor: "vscode"},
+				stack.Frame{File: "/home/me/my app/main.go", Line: 8},
+				stack.Frame{File: "Client.java", Line: 42},
+				stack.Frame{Function: "native"},
+			)
+			Expect(frames[0].URL).To(Equal("vscode://file/home/me/my%20app/main.go:8"))
+			Expect(frames[1].URL).To(BeEmpty())
+			Expect(frames[2].URL).To(BeEmpty())
+		})
+
+		It("maps path prefixes", func() {
+			frames := link(&stack.LinkerOptions{
+				Editor: "idea",
+				PathMap: []stack.PathMapping{
+					{From: "/workspace", To: "/home/me/project"},
+					{From: "/go/pkg/mod/", To: "/home/me/go/pkg/mod/"},
+				},
+			},
+				stack.Frame{File: "/workspace/main.go", Line: 8},
+				stack.Frame{File: "/workspace2/main.go", Line: 9},
+				stack.Frame{File: "/go/pkg/mod/sigs.k8s.io/controller.go", Line: 111},
+			)
+			Expect(frames[0].URL).To(Equal("idea://open?file=/home/me/project/main.go&line=8"))
+			Expect(frames[1].URL).To(Equal("idea://open?file=/workspace2/main.go&line=9"))
+			Expect(frames[2].URL).To(Equal("idea://open?file=/home/me/go/pkg/mod/sigs.k8s.io/controller.go&line=111"))
+		})
+
+		It("maps container paths to Windows paths", func() {
+			frames := link(&stack.LinkerOptions{
+				Editor:  "vscode",
+				PathMap: []stack.PathMapping{{From: "/app", To: `C:\src\app`}},
+			}, stack.Frame{File: "/app/main.go", Line: 8})
+			Expect(frames[0].URL).To(Equal("vscode://file/C:/src/app/main.go:8"))
+		})
+
+		It("accepts URL templates", func() {
+			frames := link(&stack.LinkerOptions{Editor: "zed://file{file}:{line}"},
+				stack.Frame{File: "/app/main.go", Line: 8})
+			Expect(frames[0].URL).To(Equal("zed://file/app/main.go:8"))
+		})
+
+		It("rejects unknown editors", func() {
+			_, err := stack.NewLinker(&stack.LinkerOptions{Editor: "notepad"})
+			Expect(err).To(MatchError(ContainSubstring(`unknown editor "notepad"`)))
+		})
+
+		It("leaves frames unchanged if nil", func() {
+			var linker *stack.Linker
+			frames := []stack.Frame{{File: "/app/main.go", Line: 8}}
+			linker.Link(frames)
+			Expect(frames[0].URL).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("ParsePathMapping",
+		func(input string, expected stack.PathMapping, valid bool) {
+			mapping, err := stack.ParsePathMapping(input)
+			if !valid {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapping).To(Equal(expected))
+		},
+		Entry("mapping", "/workspace=/home/me/project", stack.PathMapping{From: "/workspace", To: "/home/me/project"}, true),
+		Entry("Windows target", `/app=C:\src`, stack.PathMapping{From: "/app", To: `C:\src`}, true),
+		Entry("missing separator", "/workspace", stack.PathMapping{}, false),
+		Entry("empty target", "/workspace=", stack.PathMapping{}, false),
+	)
+})